@@ -0,0 +1,89 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type assertsCollector struct {
+	ctx  context.Context
+	base *baseCollector
+}
+
+// newAssertsCollector creates a collector for the assertion counters reported by serverStatus.asserts.
+func newAssertsCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger) *assertsCollector {
+	return &assertsCollector{
+		ctx:  ctx,
+		base: newBaseCollector(client, logger),
+	}
+}
+
+func (d *assertsCollector) Describe(ch chan<- *prometheus.Desc) {
+	d.base.Describe(d.ctx, ch, d.collect)
+}
+
+func (d *assertsCollector) Collect(ch chan<- prometheus.Metric) {
+	d.base.Collect(ch)
+}
+
+func (d *assertsCollector) collect(ch chan<- prometheus.Metric) {
+	defer measureCollectTime(ch, "mongodb", "asserts")()
+
+	logger := d.base.logger
+
+	m, err := serverStatus(d.ctx, d.base.client)
+	if err != nil {
+		logger.Errorf("cannot get asserts stats: %s", err)
+
+		return
+	}
+
+	for _, metric := range assertsMetrics(m) {
+		ch <- metric
+	}
+}
+
+// assertsMetrics returns the assertion counters from a serverStatus document.
+// All the values, including rollovers, only grow until the server restarts so all of them
+// are exposed as counters.
+func assertsMetrics(m bson.M) []prometheus.Metric {
+	asserts, ok := m["asserts"].(bson.M)
+	if !ok {
+		return nil
+	}
+
+	d := prometheus.NewDesc("mongodb_asserts_total", "Number of assertions raised since the server started.",
+		[]string{"type"}, nil)
+
+	var metrics []prometheus.Metric
+	for _, t := range []string{"regular", "warning", "msg", "user", "rollovers"} {
+		f, err := asFloat64(asserts[t])
+		if err != nil || f == nil {
+			continue
+		}
+		metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.CounterValue, *f, t))
+	}
+
+	return metrics
+}
+
+var _ prometheus.Collector = (*assertsCollector)(nil)
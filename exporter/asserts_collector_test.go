@@ -0,0 +1,76 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestAssertsMetrics(t *testing.T) {
+	m := bson.M{
+		"asserts": bson.M{
+			"regular":   int32(1),
+			"warning":   int32(2),
+			"msg":       int32(3),
+			"user":      int32(42),
+			"tripwire":  int32(0),
+			"rollovers": int32(0),
+		},
+	}
+
+	expected := strings.NewReader(`
+	# HELP mongodb_asserts_total Number of assertions raised since the server started.
+	# TYPE mongodb_asserts_total counter
+	mongodb_asserts_total{type="msg"} 3
+	mongodb_asserts_total{type="regular"} 1
+	mongodb_asserts_total{type="rollovers"} 0
+	mongodb_asserts_total{type="user"} 42
+	mongodb_asserts_total{type="warning"} 2` + "\n")
+
+	err := testutil.CollectAndCompare(metricsCollector(assertsMetrics(m)), expected)
+	assert.NoError(t, err)
+
+	assert.Empty(t, assertsMetrics(bson.M{}))
+}
+
+func TestAssertsCompatibleMode(t *testing.T) {
+	ss := bson.M{"asserts": bson.M{"regular": int32(1), "warning": int32(2), "msg": int32(3), "user": int32(42), "rollovers": int32(0)}}
+
+	// The diagnostic data collector in compatible mode exposes mongodb_asserts_total with other labels
+	// so both cannot be registered together.
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(metricsCollector(makeMetrics("", bson.M{"serverStatus": ss}, map[string]string{"rs_nm": "rs0"}, true)))
+	assert.Error(t, registry.Register(metricsCollector(assertsMetrics(ss))))
+
+	e := New(&Opts{
+		URI:                  "mongodb://127.0.0.1:12345/admin",
+		Logger:               logrus.New(),
+		CompatibleMode:       true,
+		EnableDiagnosticData: true,
+		EnableAsserts:        true,
+	})
+	assert.False(t, collectorEnabled(e.collectorStates(typeMongod, *e.opts), collectorAsserts))
+
+	e.opts.CompatibleModeCollectors = map[string]bool{collectorDiagnosticData: false}
+	assert.True(t, collectorEnabled(e.collectorStates(typeMongod, *e.opts), collectorAsserts))
+}
@@ -0,0 +1,179 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// defaultAtlasAPIURL is the base URL of the Atlas Admin API.
+const defaultAtlasAPIURL = "https://cloud.mongodb.com/api/atlas/v1.0"
+
+var errAtlasRateLimited = errors.New("Atlas API rate limit reached") //nolint:stylecheck
+
+// atlasProcesses is the response of the processes endpoint of the Atlas API.
+type atlasProcesses struct {
+	Results []struct {
+		ID string `json:"id"`
+	} `json:"results"`
+}
+
+// atlasMeasurements is the response of the measurements endpoint of a process of the Atlas API.
+type atlasMeasurements struct {
+	Measurements []struct {
+		Name       string `json:"name"`
+		Units      string `json:"units"`
+		DataPoints []struct {
+			Timestamp string   `json:"timestamp"`
+			Value     *float64 `json:"value"`
+		} `json:"dataPoints"`
+	} `json:"measurements"`
+}
+
+type atlasCollector struct {
+	ctx     context.Context
+	base    *baseCollector
+	client  *http.Client
+	baseURL string
+	groupID string
+}
+
+// newAtlasCollector creates a collector for the process measurements of the Atlas project groupID,
+// read from the Atlas Admin API at baseURL with the API key publicKey and privateKey.
+func newAtlasCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger, baseURL, groupID, publicKey, privateKey string) *atlasCollector {
+	return &atlasCollector{
+		ctx:  ctx,
+		base: newBaseCollector(client, logger),
+		client: &http.Client{
+			Transport: &digestTransport{username: publicKey, password: privateKey},
+		},
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		groupID: groupID,
+	}
+}
+
+func (d *atlasCollector) Describe(ch chan<- *prometheus.Desc) {
+	d.base.Describe(d.ctx, ch, d.collect)
+}
+
+func (d *atlasCollector) Collect(ch chan<- prometheus.Metric) {
+	d.base.Collect(ch)
+}
+
+func (d *atlasCollector) collect(ch chan<- prometheus.Metric) {
+	defer measureCollectTime(ch, "mongodb", "atlas")()
+
+	metrics, err := d.atlasMetrics()
+	if err != nil {
+		if errors.Is(err, errAtlasRateLimited) {
+			d.base.logger.Warnf("Skipping the Atlas metrics of this scrape: %s", err)
+
+			return
+		}
+		d.base.logger.Errorf("cannot get the Atlas metrics: %s", err)
+
+		return
+	}
+
+	for _, metric := range metrics {
+		ch <- metric
+	}
+}
+
+// atlasMetrics returns the last measurements of every process of the project. Nothing is returned
+// if a request fails so the metrics of a scrape are all from the same time.
+func (d *atlasCollector) atlasMetrics() ([]prometheus.Metric, error) {
+	var processes atlasProcesses
+	if err := d.get("/groups/"+url.PathEscape(d.groupID)+"/processes", url.Values{"itemsPerPage": {"500"}}, &processes); err != nil {
+		return nil, errors.Wrap(err, "cannot get the Atlas processes")
+	}
+
+	query := url.Values{"granularity": {"PT1M"}, "period": {"PT5M"}}
+
+	var metrics []prometheus.Metric
+	for _, process := range processes.Results {
+		var m atlasMeasurements
+		path := "/groups/" + url.PathEscape(d.groupID) + "/processes/" + url.PathEscape(process.ID) + "/measurements"
+		if err := d.get(path, query, &m); err != nil {
+			return nil, errors.Wrapf(err, "cannot get the Atlas measurements of %s", process.ID)
+		}
+
+		metrics = append(metrics, atlasMeasurementMetrics(process.ID, m)...)
+	}
+
+	return metrics, nil
+}
+
+func (d *atlasCollector) get(path string, query url.Values, v interface{}) error {
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodGet, d.baseURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return errors.Wrap(err, "cannot build the Atlas API request")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "cannot reach the Atlas API")
+	}
+	defer res.Body.Close() //nolint:errcheck
+
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusTooManyRequests:
+		return errAtlasRateLimited
+	default:
+		return errors.Errorf("the Atlas API answered %s", res.Status)
+	}
+
+	return errors.Wrap(json.NewDecoder(res.Body).Decode(v), "cannot decode the Atlas API response")
+}
+
+// atlasMeasurementMetrics returns a mongodb_atlas_<name> gauge with the last value of every measurement
+// of the process, like mongodb_atlas_connections for CONNECTIONS. The data points without a value,
+// because the period just started, are skipped.
+func atlasMeasurementMetrics(process string, m atlasMeasurements) []prometheus.Metric {
+	var metrics []prometheus.Metric
+
+	for _, measurement := range m.Measurements {
+		var value *float64
+		for _, point := range measurement.DataPoints {
+			if point.Value != nil {
+				value = point.Value
+			}
+		}
+
+		if value == nil {
+			continue
+		}
+
+		d := prometheus.NewDesc(prometheusize("atlas_"+strings.ToLower(measurement.Name)),
+			"Atlas measurement "+measurement.Name+" in "+measurement.Units+".", []string{"process"}, nil)
+		metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.GaugeValue, *value, process))
+	}
+
+	return metrics
+}
+
+var _ prometheus.Collector = (*atlasCollector)(nil)
@@ -0,0 +1,114 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// atlasAPIServer mocks the Atlas Admin API for the project 5e2211c17a3e5a48f5497de3. It checks the
+// digest authentication and answers 429 if rateLimited is true.
+func atlasAPIServer(t *testing.T, rateLimited bool) *httptest.Server {
+	t.Helper()
+
+	const realm, nonce = "MMS Public API", "fJOUKSpTcLpEtJbDHDbT1a6fOaY0g8S7"
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		challenge := parseDigestChallenge(r.Header.Get("Authorization"))
+		want := md5Hex(strings.Join([]string{
+			md5Hex("public:" + realm + ":private"), nonce, challenge["nc"], challenge["cnonce"], "auth",
+			md5Hex(r.Method + ":" + r.URL.RequestURI()),
+		}, ":"))
+
+		if challenge["username"] != "public" || challenge["response"] != want {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Digest realm="%s", domain="", nonce="%s", algorithm=MD5, qop="auth", stale=false`, realm, nonce))
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		if rateLimited {
+			w.WriteHeader(http.StatusTooManyRequests)
+
+			return
+		}
+
+		switch r.URL.Path {
+		case "/groups/5e2211c17a3e5a48f5497de3/processes":
+			fmt.Fprint(w, `{"results": [{"id": "atlas-abc-shard-00-00.mongodb.net:27017", "typeName": "REPLICA_PRIMARY"}], "totalCount": 1}`)
+		case "/groups/5e2211c17a3e5a48f5497de3/processes/atlas-abc-shard-00-00.mongodb.net:27017/measurements":
+			assert.Equal(t, "PT1M", r.URL.Query().Get("granularity"))
+			fmt.Fprint(w, `{"measurements": [
+				{"name": "CONNECTIONS", "units": "SCALAR", "dataPoints": [
+					{"timestamp": "2024-03-01T12:00:00Z", "value": 12},
+					{"timestamp": "2024-03-01T12:01:00Z", "value": 15},
+					{"timestamp": "2024-03-01T12:02:00Z", "value": null}
+				]},
+				{"name": "OPCOUNTER_QUERY", "units": "SCALAR_PER_SECOND", "dataPoints": [
+					{"timestamp": "2024-03-01T12:01:00Z", "value": 4.5}
+				]},
+				{"name": "CACHE_BYTES_READ_INTO", "units": "BYTES_PER_SECOND", "dataPoints": []}
+			]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestAtlasCollector(t *testing.T) {
+	ts := atlasAPIServer(t, false)
+	defer ts.Close()
+
+	c := newAtlasCollector(context.Background(), nil, logrus.New(), ts.URL, "5e2211c17a3e5a48f5497de3", "public", "private")
+
+	expected := `
+	# HELP mongodb_atlas_connections Atlas measurement CONNECTIONS in SCALAR.
+	# TYPE mongodb_atlas_connections gauge
+	mongodb_atlas_connections{process="atlas-abc-shard-00-00.mongodb.net:27017"} 15
+	# HELP mongodb_atlas_opcounter_query Atlas measurement OPCOUNTER_QUERY in SCALAR_PER_SECOND.
+	# TYPE mongodb_atlas_opcounter_query gauge
+	mongodb_atlas_opcounter_query{process="atlas-abc-shard-00-00.mongodb.net:27017"} 4.5` + "\n"
+
+	err := testutil.CollectAndCompare(c, strings.NewReader(expected), "mongodb_atlas_connections", "mongodb_atlas_opcounter_query")
+	assert.NoError(t, err)
+
+	// A wrong key is rejected.
+	c = newAtlasCollector(context.Background(), nil, logrus.New(), ts.URL, "5e2211c17a3e5a48f5497de3", "public", "wrong")
+	assert.Equal(t, 0, testutil.CollectAndCount(c, "mongodb_atlas_connections"))
+}
+
+func TestAtlasCollectorRateLimited(t *testing.T) {
+	ts := atlasAPIServer(t, true)
+	defer ts.Close()
+
+	c := newAtlasCollector(context.Background(), nil, logrus.New(), ts.URL, "5e2211c17a3e5a48f5497de3", "public", "private")
+
+	_, err := c.atlasMetrics()
+	assert.ErrorIs(t, err, errAtlasRateLimited)
+
+	// Only the scrape time of the collector is exposed.
+	assert.Equal(t, 0, testutil.CollectAndCount(c, "mongodb_atlas_connections"))
+	assert.Equal(t, 1, testutil.CollectAndCount(c))
+}
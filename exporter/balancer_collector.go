@@ -0,0 +1,136 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// migrationCommitted is the changelog event of a chunk migration committed by the donor shard.
+	migrationCommitted = "moveChunk.commit"
+	// migrationFailed is the changelog event of a chunk migration aborted by the donor shard.
+	migrationFailed = "moveChunk.error"
+)
+
+type balancerCollector struct {
+	ctx          context.Context
+	base         *baseCollector
+	configClient *mongo.Client
+}
+
+// newBalancerCollector creates a collector for the chunk migrations done by the balancer.
+// If configClient is not nil, the config database is read from it instead of through client.
+func newBalancerCollector(ctx context.Context, client, configClient *mongo.Client, logger *logrus.Logger) *balancerCollector {
+	if configClient == nil {
+		configClient = client
+	}
+
+	return &balancerCollector{
+		ctx:          ctx,
+		base:         newBaseCollector(client, logger),
+		configClient: configClient,
+	}
+}
+
+func (d *balancerCollector) Describe(ch chan<- *prometheus.Desc) {
+	d.base.Describe(d.ctx, ch, d.collect)
+}
+
+func (d *balancerCollector) Collect(ch chan<- prometheus.Metric) {
+	d.base.Collect(ch)
+}
+
+func (d *balancerCollector) collect(ch chan<- prometheus.Metric) {
+	defer measureCollectTime(ch, "mongodb", "balancer")()
+
+	logger := d.base.logger
+
+	filter := bson.M{"what": bson.M{"$in": bson.A{migrationCommitted, migrationFailed}}}
+	opts := options.Find().SetProjection(bson.M{"_id": 0, "what": 1, "time": 1})
+
+	cursor, err := d.configClient.Database("config").Collection("changelog").Find(d.ctx, filter, opts)
+	if err != nil {
+		if isUnauthorized(err) {
+			logger.Debugf("not allowed to read config.changelog: %s", err)
+
+			return
+		}
+		logger.Errorf("cannot get the chunk migrations: %s", err)
+
+		return
+	}
+
+	var changes []bson.M
+	if err := cursor.All(d.ctx, &changes); err != nil {
+		logger.Errorf("cannot decode the chunk migrations: %s", err)
+
+		return
+	}
+
+	for _, metric := range balancerMigrationMetrics(changes) {
+		ch <- metric
+	}
+}
+
+// balancerMigrationMetrics returns the number of successful and failed chunk migrations and the
+// time of the last one from the config.changelog documents. The changelog is a capped collection
+// so only the migrations still in it are counted.
+func balancerMigrationMetrics(changes []bson.M) []prometheus.Metric {
+	var succeeded, failed float64
+	var last primitive.DateTime
+
+	for _, change := range changes {
+		switch change["what"] {
+		case migrationCommitted:
+			succeeded++
+		case migrationFailed:
+			failed++
+		default:
+			continue
+		}
+
+		if t, ok := change["time"].(primitive.DateTime); ok && t > last {
+			last = t
+		}
+	}
+
+	migrations := prometheus.NewDesc("mongodb_balancer_migrations_total",
+		"The number of chunk migrations in config.changelog by result.", []string{"result"}, nil)
+
+	metrics := []prometheus.Metric{
+		prometheus.MustNewConstMetric(migrations, prometheus.CounterValue, succeeded, "success"),
+		prometheus.MustNewConstMetric(migrations, prometheus.CounterValue, failed, "failed"),
+	}
+
+	if last != 0 {
+		d := prometheus.NewDesc("mongodb_balancer_last_migration_timestamp_seconds",
+			"The time of the last chunk migration in config.changelog.", nil, nil)
+		metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.GaugeValue,
+			float64(last.Time().UnixNano())/1e9))
+	}
+
+	return metrics
+}
+
+var _ prometheus.Collector = (*balancerCollector)(nil)
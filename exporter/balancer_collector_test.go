@@ -0,0 +1,60 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestBalancerMigrationMetrics(t *testing.T) {
+	last := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	changes := []bson.M{
+		{"what": "moveChunk.commit", "time": primitive.NewDateTimeFromTime(last.Add(-time.Hour))},
+		{"what": "moveChunk.error", "time": primitive.NewDateTimeFromTime(last.Add(-30 * time.Minute))},
+		{"what": "moveChunk.commit", "time": primitive.NewDateTimeFromTime(last)},
+		// Other changes are ignored.
+		{"what": "split", "time": primitive.NewDateTimeFromTime(last.Add(time.Hour))},
+	}
+
+	expected := `
+	# HELP mongodb_balancer_last_migration_timestamp_seconds The time of the last chunk migration in config.changelog.
+	# TYPE mongodb_balancer_last_migration_timestamp_seconds gauge
+	mongodb_balancer_last_migration_timestamp_seconds 1.7092944e+09
+	# HELP mongodb_balancer_migrations_total The number of chunk migrations in config.changelog by result.
+	# TYPE mongodb_balancer_migrations_total counter
+	mongodb_balancer_migrations_total{result="failed"} 1
+	mongodb_balancer_migrations_total{result="success"} 2` + "\n"
+
+	err := testutil.CollectAndCompare(metricsCollector(balancerMigrationMetrics(changes)), strings.NewReader(expected))
+	assert.NoError(t, err)
+
+	// Without migrations, there is no last migration time.
+	expected = `
+	# HELP mongodb_balancer_migrations_total The number of chunk migrations in config.changelog by result.
+	# TYPE mongodb_balancer_migrations_total counter
+	mongodb_balancer_migrations_total{result="failed"} 0
+	mongodb_balancer_migrations_total{result="success"} 0` + "\n"
+
+	err = testutil.CollectAndCompare(metricsCollector(balancerMigrationMetrics(nil)), strings.NewReader(expected))
+	assert.NoError(t, err)
+}
@@ -17,7 +17,9 @@ package exporter
 
 import (
 	"context"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
@@ -34,10 +36,17 @@ type collstatsCollector struct {
 	topologyInfo    labelsGetter
 
 	collections []string
+	concurrency int
+	// wiredTiger exposes the WiredTiger cache and block manager stats of every collection.
+	wiredTiger bool
+	// indexSizes exposes the size of every index with the index name as a label.
+	indexSizes bool
+	// topN, in discovering mode, restricts the stats to the topN collections using the most storage.
+	topN int
 }
 
 // newCollectionStatsCollector creates a collector for statistics about collections.
-func newCollectionStatsCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger, compatible, discovery bool, topology labelsGetter, collections []string) *collstatsCollector {
+func newCollectionStatsCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger, compatible, discovery bool, topology labelsGetter, collections []string, concurrency int) *collstatsCollector {
 	return &collstatsCollector{
 		ctx:  ctx,
 		base: newBaseCollector(client, logger),
@@ -47,6 +56,7 @@ func newCollectionStatsCollector(ctx context.Context, client *mongo.Client, logg
 		topologyInfo:    topology,
 
 		collections: collections,
+		concurrency: concurrency,
 	}
 }
 
@@ -64,9 +74,11 @@ func (d *collstatsCollector) collect(ch chan<- prometheus.Metric) {
 	client := d.base.client
 	logger := d.base.logger
 
+	timeseries := make(map[string]bool)
+
 	var collections []string
 	if d.discoveringMode {
-		onlyCollectionsNamespaces, err := listAllCollections(d.ctx, client, d.collections, systemDBs, true)
+		onlyCollectionsNamespaces, err := cachedListAllCollections(d.ctx, client, d.collections, systemDBs, true)
 		if err != nil {
 			logger.Errorf("cannot auto discover databases and collections: %s", err.Error())
 
@@ -74,71 +86,364 @@ func (d *collstatsCollector) collect(ch chan<- prometheus.Metric) {
 		}
 
 		collections = fromMapToSlice(onlyCollectionsNamespaces)
+
+		tsNamespaces, err := cachedListTimeseriesCollections(d.ctx, client, d.collections, systemDBs)
+		if err != nil {
+			logger.Errorf("cannot list time-series collections: %s", err.Error())
+		}
+
+		for _, ns := range tsNamespaces {
+			timeseries[ns] = true
+			collections = append(collections, ns)
+		}
+
+		if d.topN > 0 && len(collections) > d.topN {
+			collections = topCollections(d.collectionSizes(collections), d.topN)
+		}
 	} else {
-		var err error
-		collections, err = checkNamespacesForViews(d.ctx, client, d.collections)
+		regular, others, err := splitRegularCollections(d.ctx, client, d.collections)
 		if err != nil {
 			logger.Errorf("cannot list collections: %s", err.Error())
 
 			return
 		}
+
+		// Only the namespaces which are not regular collections can be time-series ones,
+		// the other ones are views.
+		if len(others) > 0 {
+			tsNamespaces, err := cachedListTimeseriesCollections(d.ctx, client, others, systemDBs)
+			if err != nil {
+				logger.Errorf("cannot list time-series collections: %s", err.Error())
+			}
+
+			for _, ns := range tsNamespaces {
+				timeseries[ns] = true
+			}
+		}
+
+		collections = regular
+		for _, ns := range others {
+			if !timeseries[ns] {
+				logger.Errorf("cannot list collections: namespace %s is a view and cannot be used for collstats/indexstats", ns)
+
+				return
+			}
+
+			collections = append(collections, ns)
+		}
 	}
 
-	for _, dbCollection := range collections {
-		parts := strings.Split(dbCollection, ".")
-		if len(parts) < 2 { //nolint:gomnd
-			continue
+	results := runConcurrently(d.concurrency, collections, func(dbCollection string) []prometheus.Metric {
+		return d.collectionMetrics(dbCollection, timeseries[dbCollection])
+	})
+
+	for _, metrics := range results {
+		for _, metric := range metrics {
+			ch <- metric
 		}
+	}
+}
 
-		database := parts[0]
-		collection := strings.Join(parts[1:], ".") // support collections having a .
+// collectionMetrics runs $collStats for a single namespace and returns its metrics.
+// Errors are logged so a failing collection doesn't prevent getting the stats of the others.
+func (d *collstatsCollector) collectionMetrics(dbCollection string, isTimeseries bool) []prometheus.Metric {
+	client := d.base.client
+	logger := d.base.logger
 
-		aggregation := bson.D{
-			{
-				Key: "$collStats", Value: bson.M{
-					// TODO: PMM-9568 : Add support to handle histogram metrics
-					"latencyStats": bson.M{"histograms": false},
-					"storageStats": bson.M{"scale": 1},
-				},
+	parts := strings.Split(dbCollection, ".")
+	if len(parts) < 2 { //nolint:gomnd
+		return nil
+	}
+
+	database := parts[0]
+	collection := strings.Join(parts[1:], ".") // support collections having a .
+
+	aggregation := bson.D{
+		{
+			Key: "$collStats", Value: bson.M{
+				// TODO: PMM-9568 : Add support to handle histogram metrics
+				"latencyStats": bson.M{"histograms": false},
+				"storageStats": bson.M{"scale": 1},
 			},
+		},
+	}
+	excluded := bson.M{"storageStats.indexDetails": 0}
+	if !d.wiredTiger {
+		excluded["storageStats.wiredTiger"] = 0
+	}
+	project := bson.D{{Key: "$project", Value: excluded}}
+
+	cursor, err := client.Database(database).Collection(collection).Aggregate(d.ctx, mongo.Pipeline{aggregation, project})
+	if err != nil {
+		logger.Errorf("cannot get $collstats cursor for collection %s.%s: %s", database, collection, err)
+
+		// The collection was dropped, don't keep it in the discovery results.
+		if isNamespaceNotFound(err) {
+			invalidateDiscoveryCache(d.ctx)
 		}
-		project := bson.D{
-			{
-				Key: "$project", Value: bson.M{
-					"storageStats.wiredTiger":   0,
-					"storageStats.indexDetails": 0,
-				},
-			},
+
+		return nil
+	}
+
+	var stats []bson.M
+	if err = cursor.All(d.ctx, &stats); err != nil {
+		logger.Errorf("cannot get $collstats for collection %s.%s: %s", database, collection, err)
+
+		if isNamespaceNotFound(err) {
+			invalidateDiscoveryCache(d.ctx)
+		}
+
+		return nil
+	}
+
+	logger.Debugf("$collStats metrics for %s.%s", database, collection)
+	debugResult(logger, stats)
+
+	prefix := "collstats"
+	labels := d.topologyInfo.baseLabels()
+	labels["database"] = database
+	labels["collection"] = collection
+
+	var metrics []prometheus.Metric
+	for _, s := range stats {
+		if d.wiredTiger {
+			metrics = append(metrics, collectionWiredTigerMetrics(s, labels)...)
+
+			// The stats of the section are too many to be exposed as they are.
+			if storageStats, ok := s["storageStats"].(bson.M); ok {
+				delete(storageStats, "wiredTiger")
+			}
+		}
+
+		if d.indexSizes {
+			metrics = append(metrics, indexSizeMetrics(s, labels)...)
 		}
 
-		cursor, err := client.Database(database).Collection(collection).Aggregate(d.ctx, mongo.Pipeline{aggregation, project})
+		metrics = append(metrics, makeMetrics(prefix, s, labels, d.compatibleMode)...)
+
+		if isTimeseries {
+			metrics = append(metrics, timeseriesMetrics(s, labels)...)
+		}
+	}
+
+	return metrics
+}
+
+// collectionSizes returns the storage size of the collections. The collections whose size cannot be
+// read are not included.
+func (d *collstatsCollector) collectionSizes(collections []string) map[string]float64 {
+	sizes := make(map[string]float64, len(collections))
+
+	var lock sync.Mutex
+	runConcurrently(d.concurrency, collections, func(dbCollection string) []prometheus.Metric {
+		size, err := d.collectionSize(dbCollection)
 		if err != nil {
-			logger.Errorf("cannot get $collstats cursor for collection %s.%s: %s", database, collection, err)
+			d.base.logger.Errorf("cannot get the storage size of %s: %s", dbCollection, err)
 
-			continue
+			return nil
 		}
 
-		var stats []bson.M
-		if err = cursor.All(d.ctx, &stats); err != nil {
-			logger.Errorf("cannot get $collstats for collection %s.%s: %s", database, collection, err)
+		lock.Lock()
+		sizes[dbCollection] = size
+		lock.Unlock()
 
-			continue
+		return nil
+	})
+
+	return sizes
+}
+
+// collectionSize returns the storage size of a collection, summed over the shards for a sharded
+// collection. Only the size is returned by $collStats so it is cheap compared to the full stats.
+func (d *collstatsCollector) collectionSize(dbCollection string) (float64, error) {
+	database, collection := splitNamespace(dbCollection)
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$collStats", Value: bson.M{"storageStats": bson.M{"scale": 1}}}},
+		{{Key: "$project", Value: bson.M{"_id": 0, "storageSize": "$storageStats.storageSize"}}},
+	}
+
+	cursor, err := d.base.client.Database(database).Collection(collection).Aggregate(d.ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+
+	var stats []bson.M
+	if err := cursor.All(d.ctx, &stats); err != nil {
+		return 0, err
+	}
+
+	var size float64
+	for _, s := range stats {
+		if f, err := asFloat64(s["storageSize"]); err == nil && f != nil {
+			size += *f
+		}
+	}
+
+	return size, nil
+}
+
+// topCollections returns the n namespaces having the largest sizes, from the largest. Namespaces
+// with the same size are sorted by name so the same collections are selected on every scrape.
+func topCollections(sizes map[string]float64, n int) []string {
+	namespaces := make([]string, 0, len(sizes))
+	for ns := range sizes {
+		namespaces = append(namespaces, ns)
+	}
+
+	sort.Slice(namespaces, func(i, j int) bool {
+		if sizes[namespaces[i]] != sizes[namespaces[j]] {
+			return sizes[namespaces[i]] > sizes[namespaces[j]]
+		}
+
+		return namespaces[i] < namespaces[j]
+	})
+
+	if len(namespaces) > n {
+		namespaces = namespaces[:n]
+	}
+
+	return namespaces
+}
+
+// runConcurrently calls fn for every namespace using at most concurrency goroutines.
+// The results are returned in the same order as the namespaces so the metrics are always
+// emitted in the same order regardless of the concurrency.
+func runConcurrently(concurrency int, namespaces []string, fn func(string) []prometheus.Metric) [][]prometheus.Metric {
+	results := make([][]prometheus.Metric, len(namespaces))
+
+	if concurrency <= 1 {
+		for i, ns := range namespaces {
+			results[i] = fn(ns)
 		}
 
-		logger.Debugf("$collStats metrics for %s.%s", database, collection)
-		debugResult(logger, stats)
+		return results
+	}
 
-		prefix := "collstats"
-		labels := d.topologyInfo.baseLabels()
-		labels["database"] = database
-		labels["collection"] = collection
+	jobs := make(chan int)
+	var wg sync.WaitGroup
 
-		for _, metrics := range stats {
-			for _, metric := range makeMetrics(prefix, metrics, labels, d.compatibleMode) {
-				ch <- metric
+	for w := 0; w < concurrency && w < len(namespaces); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = fn(namespaces[i])
 			}
+		}()
+	}
+
+	for i := range namespaces {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// timeseriesMetrics returns the bucket and measurement counts from the timeseries section
+// of the $collStats result for a time-series collection.
+func timeseriesMetrics(stats bson.M, labels map[string]string) []prometheus.Metric {
+	ts, ok := walkTo(stats, []string{"storageStats", "timeseries"}).(bson.M)
+	if !ok {
+		return nil
+	}
+	labels = withShardLabel(stats, labels)
+
+	var metrics []prometheus.Metric
+	createMetric := func(name, help string, keys ...string) {
+		for _, key := range keys {
+			f, err := asFloat64(ts[key])
+			if err != nil || f == nil {
+				continue
+			}
+
+			d := prometheus.NewDesc(name, help, nil, labels)
+			metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.GaugeValue, *f))
+
+			return
+		}
+	}
+
+	createMetric("mongodb_timeseries_bucket_count", "Number of buckets in the time-series collection.",
+		"bucketCount")
+	// Older versions report numMeasurementsCommitted instead.
+	createMetric("mongodb_timeseries_num_measurements", "Number of measurements in the time-series collection.",
+		"numMeasurements", "numMeasurementsCommitted")
+
+	return metrics
+}
+
+// withShardLabel returns a copy of the labels with the shard of a $collStats result. Through
+// mongos, $collStats returns a result per shard for a sharded collection, so the shard tells
+// their series apart. The labels are returned as they are for the other results.
+func withShardLabel(stats bson.M, labels map[string]string) map[string]string {
+	shard, ok := stats["shard"].(string)
+	if !ok || shard == "" {
+		return labels
+	}
+
+	res := make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		res[k] = v
+	}
+	res["shard"] = shard
+
+	return res
+}
+
+// collectionWiredTigerMetrics returns the cache usage and the blocks read of a collection from the
+// wiredTiger section of its $collStats result. Collections using another storage engine have
+// no such section so no metrics are returned for them.
+func collectionWiredTigerMetrics(stats bson.M, labels map[string]string) []prometheus.Metric {
+	wt, ok := walkTo(stats, []string{"storageStats", "wiredTiger"}).(bson.M)
+	if !ok {
+		return nil
+	}
+	labels = withShardLabel(stats, labels)
+
+	var metrics []prometheus.Metric
+	createMetric := func(name, help string, valueType prometheus.ValueType, path ...string) {
+		f, err := asFloat64(walkTo(wt, path))
+		if err != nil || f == nil {
+			return
 		}
+
+		d := prometheus.NewDesc(name, help, nil, labels)
+		metrics = append(metrics, prometheus.MustNewConstMetric(d, valueType, *f))
+	}
+
+	createMetric("mongodb_collection_wiredtiger_cache_bytes", "Size in bytes of the collection data in the WiredTiger cache.",
+		prometheus.GaugeValue, "cache", "bytes currently in the cache")
+	createMetric("mongodb_collection_wiredtiger_blocks_read_total", "Number of blocks of the collection read from disk by WiredTiger.",
+		prometheus.CounterValue, "block-manager", "blocks read")
+
+	return metrics
+}
+
+// indexSizeMetrics returns the size of every index of a collection from the indexSizes section of
+// its $collStats result.
+func indexSizeMetrics(stats bson.M, labels map[string]string) []prometheus.Metric {
+	sizes, ok := walkTo(stats, []string{"storageStats", "indexSizes"}).(bson.M)
+	if !ok {
+		return nil
 	}
+
+	d := prometheus.NewDesc("mongodb_collection_index_size_bytes", "Size in bytes of the index.", []string{"index"},
+		withShardLabel(stats, labels))
+
+	metrics := make([]prometheus.Metric, 0, len(sizes))
+	for index, size := range sizes {
+		f, err := asFloat64(size)
+		if err != nil || f == nil {
+			continue
+		}
+
+		metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.GaugeValue, *f, index))
+	}
+
+	return metrics
 }
 
 var _ prometheus.Collector = (*collstatsCollector)(nil)
@@ -19,9 +19,11 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
@@ -53,7 +55,7 @@ func TestCollStatsCollector(t *testing.T) {
 	ti := labelsGetterMock{}
 
 	collection := []string{"testdb.testcol_00", "testdb.testcol_01", "testdb.testcol_02"}
-	c := newCollectionStatsCollector(ctx, client, logrus.New(), false, false, ti, collection)
+	c := newCollectionStatsCollector(ctx, client, logrus.New(), false, false, ti, collection, 1)
 
 	// The last \n at the end of this string is important
 	expected := strings.NewReader(`
@@ -86,3 +88,232 @@ mongodb_collstats_storageStats_capped{collection="testcol_02",database="testdb"}
 	err := testutil.CollectAndCompare(c, expected, filter...)
 	assert.NoError(t, err)
 }
+
+func TestTimeseriesMetrics(t *testing.T) {
+	stats := bson.M{
+		"ns": "testdb.weather",
+		"storageStats": bson.M{
+			"size":  int32(1024),
+			"count": int32(5),
+			"timeseries": bson.M{
+				"bucketsNs":        "testdb.system.buckets.weather",
+				"bucketCount":      int32(12),
+				"avgBucketSize":    int32(85),
+				"numBucketInserts": int64(12),
+				"numMeasurements":  int64(1500),
+			},
+		},
+	}
+	labels := map[string]string{"database": "testdb", "collection": "weather"}
+
+	expected := strings.NewReader(`
+	# HELP mongodb_timeseries_bucket_count Number of buckets in the time-series collection.
+	# TYPE mongodb_timeseries_bucket_count gauge
+	mongodb_timeseries_bucket_count{collection="weather",database="testdb"} 12
+	# HELP mongodb_timeseries_num_measurements Number of measurements in the time-series collection.
+	# TYPE mongodb_timeseries_num_measurements gauge
+	mongodb_timeseries_num_measurements{collection="weather",database="testdb"} 1500` + "\n")
+
+	err := testutil.CollectAndCompare(metricsCollector(timeseriesMetrics(stats, labels)), expected)
+	assert.NoError(t, err)
+
+	// Through mongos, there is a result per shard.
+	shard0 := bson.M{"shard": "rs0", "storageStats": bson.M{"timeseries": bson.M{"bucketCount": int32(7)}}}
+	shard1 := bson.M{"shard": "rs1", "storageStats": bson.M{"timeseries": bson.M{"bucketCount": int32(5)}}}
+
+	expected = strings.NewReader(`
+	# HELP mongodb_timeseries_bucket_count Number of buckets in the time-series collection.
+	# TYPE mongodb_timeseries_bucket_count gauge
+	mongodb_timeseries_bucket_count{collection="weather",database="testdb",shard="rs0"} 7
+	mongodb_timeseries_bucket_count{collection="weather",database="testdb",shard="rs1"} 5` + "\n")
+
+	metrics := append(timeseriesMetrics(shard0, labels), timeseriesMetrics(shard1, labels)...)
+	err = testutil.CollectAndCompare(metricsCollector(metrics), expected)
+	assert.NoError(t, err)
+
+	// Regular collections don't have the timeseries section.
+	regular := bson.M{"storageStats": bson.M{"size": int32(1024), "count": int32(5)}}
+	assert.Empty(t, timeseriesMetrics(regular, labels))
+}
+
+func TestCollectionWiredTigerMetrics(t *testing.T) {
+	stats := bson.M{
+		"ns": "testdb.orders",
+		"storageStats": bson.M{
+			"size":  int32(4096),
+			"count": int32(20),
+			"wiredTiger": bson.M{
+				"metadata": bson.M{"formatVersion": int32(1)},
+				"block-manager": bson.M{
+					"blocks read":    int32(320),
+					"blocks written": int32(45),
+				},
+				"cache": bson.M{
+					"bytes currently in the cache": int64(524288),
+					"bytes read into cache":        int64(1048576),
+				},
+			},
+		},
+	}
+	labels := map[string]string{"database": "testdb", "collection": "orders"}
+
+	expected := strings.NewReader(`
+	# HELP mongodb_collection_wiredtiger_blocks_read_total Number of blocks of the collection read from disk by WiredTiger.
+	# TYPE mongodb_collection_wiredtiger_blocks_read_total counter
+	mongodb_collection_wiredtiger_blocks_read_total{collection="orders",database="testdb"} 320
+	# HELP mongodb_collection_wiredtiger_cache_bytes Size in bytes of the collection data in the WiredTiger cache.
+	# TYPE mongodb_collection_wiredtiger_cache_bytes gauge
+	mongodb_collection_wiredtiger_cache_bytes{collection="orders",database="testdb"} 524288` + "\n")
+
+	err := testutil.CollectAndCompare(metricsCollector(collectionWiredTigerMetrics(stats, labels)), expected)
+	assert.NoError(t, err)
+
+	// Through mongos, there is a result per shard.
+	shard0 := bson.M{"shard": "rs0", "storageStats": bson.M{"wiredTiger": bson.M{"block-manager": bson.M{"blocks read": int32(10)}}}}
+	shard1 := bson.M{"shard": "rs1", "storageStats": bson.M{"wiredTiger": bson.M{"block-manager": bson.M{"blocks read": int32(20)}}}}
+
+	expected = strings.NewReader(`
+	# HELP mongodb_collection_wiredtiger_blocks_read_total Number of blocks of the collection read from disk by WiredTiger.
+	# TYPE mongodb_collection_wiredtiger_blocks_read_total counter
+	mongodb_collection_wiredtiger_blocks_read_total{collection="orders",database="testdb",shard="rs0"} 10
+	mongodb_collection_wiredtiger_blocks_read_total{collection="orders",database="testdb",shard="rs1"} 20` + "\n")
+
+	metrics := append(collectionWiredTigerMetrics(shard0, labels), collectionWiredTigerMetrics(shard1, labels)...)
+	err = testutil.CollectAndCompare(metricsCollector(metrics), expected)
+	assert.NoError(t, err)
+	assert.NotContains(t, labels, "shard", "the labels of the caller must not be modified")
+
+	// Collections of other storage engines don't have the wiredTiger section.
+	inMemory := bson.M{"storageStats": bson.M{"size": int32(4096), "count": int32(20)}}
+	assert.Empty(t, collectionWiredTigerMetrics(inMemory, labels))
+}
+
+func TestIndexSizeMetrics(t *testing.T) {
+	longName := "customer_id_1_created_at_-1_status_1_" + strings.Repeat("x", 100)
+	stats := bson.M{
+		"ns": "testdb.orders",
+		"storageStats": bson.M{
+			"size":           int32(4096),
+			"totalIndexSize": int32(77824),
+			"indexSizes": bson.M{
+				"_id_":          int32(36864),
+				"customer_id_1": int32(20480),
+				longName:        int64(20480),
+			},
+		},
+	}
+	labels := map[string]string{"database": "testdb", "collection": "orders"}
+
+	expected := strings.NewReader(`
+	# HELP mongodb_collection_index_size_bytes Size in bytes of the index.
+	# TYPE mongodb_collection_index_size_bytes gauge
+	mongodb_collection_index_size_bytes{collection="orders",database="testdb",index="_id_"} 36864
+	mongodb_collection_index_size_bytes{collection="orders",database="testdb",index="customer_id_1"} 20480
+	mongodb_collection_index_size_bytes{collection="orders",database="testdb",index="` + longName + `"} 20480` + "\n")
+
+	err := testutil.CollectAndCompare(metricsCollector(indexSizeMetrics(stats, labels)), expected)
+	assert.NoError(t, err)
+
+	// Through mongos, there is a result per shard.
+	shard0 := bson.M{"shard": "rs0", "storageStats": bson.M{"indexSizes": bson.M{"_id_": int32(8192)}}}
+	shard1 := bson.M{"shard": "rs1", "storageStats": bson.M{"indexSizes": bson.M{"_id_": int32(4096)}}}
+
+	expected = strings.NewReader(`
+	# HELP mongodb_collection_index_size_bytes Size in bytes of the index.
+	# TYPE mongodb_collection_index_size_bytes gauge
+	mongodb_collection_index_size_bytes{collection="orders",database="testdb",index="_id_",shard="rs0"} 8192
+	mongodb_collection_index_size_bytes{collection="orders",database="testdb",index="_id_",shard="rs1"} 4096` + "\n")
+
+	metrics := append(indexSizeMetrics(shard0, labels), indexSizeMetrics(shard1, labels)...)
+	err = testutil.CollectAndCompare(metricsCollector(metrics), expected)
+	assert.NoError(t, err)
+
+	assert.Empty(t, indexSizeMetrics(bson.M{"storageStats": bson.M{"size": int32(4096)}}, labels))
+}
+
+func TestRunConcurrently(t *testing.T) {
+	namespaces := make([]string, 50)
+	for i := range namespaces {
+		namespaces[i] = fmt.Sprintf("db%d.col%d", i%5, i)
+	}
+
+	desc := prometheus.NewDesc("test_metric", "Test metric.", []string{"ns"}, nil)
+
+	for _, concurrency := range []int{0, 1, 4, 100} {
+		concurrency := concurrency
+		t.Run(fmt.Sprintf("concurrency %d", concurrency), func(t *testing.T) {
+			var running, maxRunning int32
+
+			results := runConcurrently(concurrency, namespaces, func(ns string) []prometheus.Metric {
+				n := atomic.AddInt32(&running, 1)
+				defer atomic.AddInt32(&running, -1)
+				for {
+					m := atomic.LoadInt32(&maxRunning)
+					if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+						break
+					}
+				}
+				time.Sleep(time.Millisecond)
+
+				// A failing namespace must not prevent getting the metrics of the others.
+				if ns == "db3.col13" {
+					return nil
+				}
+
+				return []prometheus.Metric{prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 1, ns)}
+			})
+
+			assert.Len(t, results, len(namespaces))
+			for i, metrics := range results {
+				if namespaces[i] == "db3.col13" {
+					assert.Empty(t, metrics)
+
+					continue
+				}
+				assert.Len(t, metrics, 1)
+				assert.Contains(t, metrics[0].Desc().String(), "test_metric")
+			}
+
+			want := int32(concurrency)
+			if want < 1 {
+				want = 1
+			}
+			assert.LessOrEqual(t, atomic.LoadInt32(&maxRunning), want)
+		})
+	}
+}
+
+func BenchmarkRunConcurrently(b *testing.B) {
+	namespaces := make([]string, 100)
+	for i := range namespaces {
+		namespaces[i] = fmt.Sprintf("db.col%d", i)
+	}
+
+	for _, concurrency := range []int{1, 10} {
+		b.Run(fmt.Sprintf("concurrency %d", concurrency), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				runConcurrently(concurrency, namespaces, func(string) []prometheus.Metric {
+					// Simulate the round trip of a $collStats command.
+					time.Sleep(100 * time.Microsecond)
+
+					return nil
+				})
+			}
+		})
+	}
+}
+
+func TestTopCollections(t *testing.T) {
+	sizes := map[string]float64{
+		"db1.small":   4096,
+		"db1.largest": 1 << 30,
+		"db2.tied_b":  1 << 20,
+		"db2.tied_a":  1 << 20,
+		"db3.empty":   0,
+	}
+
+	assert.Equal(t, []string{"db1.largest", "db2.tied_a", "db2.tied_b"}, topCollections(sizes, 3))
+	// Ties are broken by namespace.
+	assert.Equal(t, []string{"db1.largest", "db2.tied_a"}, topCollections(sizes, 2))
+	assert.Len(t, topCollections(sizes, 10), len(sizes))
+}
@@ -0,0 +1,121 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// unknownCommand counts the commands the server doesn't know in metrics.commands.
+const unknownCommand = "<UNKNOWN>"
+
+type commandsCollector struct {
+	ctx       context.Context
+	base      *baseCollector
+	allowlist []string
+}
+
+// newCommandsCollector creates a collector for the executions per command reported by serverStatus.
+// If allowlist is not empty, only these commands are collected.
+func newCommandsCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger, allowlist []string) *commandsCollector {
+	return &commandsCollector{
+		ctx:       ctx,
+		base:      newBaseCollector(client, logger),
+		allowlist: removeEmptyStrings(allowlist),
+	}
+}
+
+func (d *commandsCollector) Describe(ch chan<- *prometheus.Desc) {
+	d.base.Describe(d.ctx, ch, d.collect)
+}
+
+func (d *commandsCollector) Collect(ch chan<- prometheus.Metric) {
+	d.base.Collect(ch)
+}
+
+func (d *commandsCollector) collect(ch chan<- prometheus.Metric) {
+	defer measureCollectTime(ch, "mongodb", "commands")()
+
+	logger := d.base.logger
+
+	m, err := serverStatus(d.ctx, d.base.client)
+	if err != nil {
+		logger.Errorf("cannot get command metrics: %s", err)
+
+		return
+	}
+
+	for _, metric := range commandMetrics(m, d.allowlist) {
+		ch <- metric
+	}
+}
+
+// commandMetrics returns the total and failed executions per command from the metrics.commands
+// section of a serverStatus document. If allowlist is not empty, only these commands are returned.
+// <UNKNOWN> is a single counter and it is only returned if it is in the allowlist.
+func commandMetrics(m bson.M, allowlist []string) []prometheus.Metric {
+	commands, ok := walkTo(m, []string{"metrics", "commands"}).(bson.M)
+	if !ok {
+		return nil
+	}
+
+	allowed := make(map[string]bool, len(allowlist))
+	for _, command := range allowlist {
+		allowed[command] = true
+	}
+
+	totalDesc := prometheus.NewDesc("mongodb_metrics_commands_total",
+		"Number of times the command was executed.", []string{"command"}, nil)
+	failedDesc := prometheus.NewDesc("mongodb_metrics_commands_failed_total",
+		"Number of times the command failed.", []string{"command"}, nil)
+
+	var metrics []prometheus.Metric
+
+	for command, v := range commands {
+		if len(allowed) > 0 && !allowed[command] {
+			continue
+		}
+
+		if command == unknownCommand {
+			if f, err := asFloat64(v); err == nil && f != nil && allowed[command] {
+				metrics = append(metrics, prometheus.MustNewConstMetric(totalDesc, prometheus.CounterValue, *f, command))
+			}
+
+			continue
+		}
+
+		stats, ok := v.(bson.M)
+		if !ok {
+			continue
+		}
+
+		if f, err := asFloat64(stats["total"]); err == nil && f != nil {
+			metrics = append(metrics, prometheus.MustNewConstMetric(totalDesc, prometheus.CounterValue, *f, command))
+		}
+		if f, err := asFloat64(stats["failed"]); err == nil && f != nil {
+			metrics = append(metrics, prometheus.MustNewConstMetric(failedDesc, prometheus.CounterValue, *f, command))
+		}
+	}
+
+	return metrics
+}
+
+var _ prometheus.Collector = (*commandsCollector)(nil)
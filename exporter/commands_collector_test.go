@@ -0,0 +1,70 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestCommandMetrics(t *testing.T) {
+	m := bson.M{
+		"metrics": bson.M{
+			"commands": bson.M{
+				"<UNKNOWN>": int64(3),
+				"find":      bson.M{"failed": int64(2), "total": int64(1200)},
+				"insert":    bson.M{"failed": int64(0), "total": int64(450)},
+				"update": bson.M{
+					"arrayFilters": int64(0),
+					"failed":       int64(5),
+					"pipeline":     int64(0),
+					"total":        int64(300),
+				},
+			},
+		},
+	}
+
+	expected := `
+	# HELP mongodb_metrics_commands_failed_total Number of times the command failed.
+	# TYPE mongodb_metrics_commands_failed_total counter
+	mongodb_metrics_commands_failed_total{command="find"} 2
+	mongodb_metrics_commands_failed_total{command="insert"} 0
+	mongodb_metrics_commands_failed_total{command="update"} 5
+	# HELP mongodb_metrics_commands_total Number of times the command was executed.
+	# TYPE mongodb_metrics_commands_total counter
+	mongodb_metrics_commands_total{command="find"} 1200
+	mongodb_metrics_commands_total{command="insert"} 450
+	mongodb_metrics_commands_total{command="update"} 300` + "\n"
+
+	err := testutil.CollectAndCompare(metricsCollector(commandMetrics(m, nil)), strings.NewReader(expected))
+	assert.NoError(t, err)
+
+	expected = `
+	# HELP mongodb_metrics_commands_failed_total Number of times the command failed.
+	# TYPE mongodb_metrics_commands_failed_total counter
+	mongodb_metrics_commands_failed_total{command="update"} 5
+	# HELP mongodb_metrics_commands_total Number of times the command was executed.
+	# TYPE mongodb_metrics_commands_total counter
+	mongodb_metrics_commands_total{command="<UNKNOWN>"} 3
+	mongodb_metrics_commands_total{command="update"} 300` + "\n"
+
+	err = testutil.CollectAndCompare(metricsCollector(commandMetrics(m, []string{"update", "<UNKNOWN>"})), strings.NewReader(expected))
+	assert.NoError(t, err)
+}
@@ -32,6 +32,17 @@ import (
 var systemDBs = []string{"admin", "config", "local"} //nolint:gochecknoglobals
 
 func listCollections(ctx context.Context, client *mongo.Client, database string, filterInNamespaces []string, skipViews bool) ([]string, error) {
+	var collectionType string
+	if skipViews {
+		collectionType = "collection"
+	}
+
+	return listCollectionsOfType(ctx, client, database, filterInNamespaces, collectionType)
+}
+
+// listCollectionsOfType is like listCollections but it lists only the collections of the given
+// type, like "collection" or "timeseries". All types are listed if it is empty.
+func listCollectionsOfType(ctx context.Context, client *mongo.Client, database string, filterInNamespaces []string, collectionType string) ([]string, error) {
 	opts := &options.ListCollectionsOptions{NameOnly: pointer.ToBool(true), AuthorizedCollections: pointer.ToBool(true)}
 	filter := bson.D{} // Default=empty -> list all collections
 
@@ -59,8 +70,8 @@ func listCollections(ctx context.Context, client *mongo.Client, database string,
 		}
 	}
 
-	if skipViews {
-		filter = append(filter, primitive.E{Key: "type", Value: "collection"})
+	if collectionType != "" {
+		filter = append(filter, primitive.E{Key: "type", Value: collectionType})
 	}
 
 	collections, err := client.Database(database).ListCollectionNames(ctx, filter, opts)
@@ -160,11 +171,26 @@ func unique(slice []string) []string {
 }
 
 func checkNamespacesForViews(ctx context.Context, client *mongo.Client, collections []string) ([]string, error) {
-	onlyCollectionsNamespaces, err := listAllCollections(ctx, client, nil, nil, true)
+	regular, others, err := splitRegularCollections(ctx, client, collections)
 	if err != nil {
 		return nil, err
 	}
 
+	if len(others) > 0 {
+		return nil, errors.Errorf("namespace %s is a view and cannot be used for collstats/indexstats", others[0])
+	}
+
+	return regular, nil
+}
+
+// splitRegularCollections splits the db.collection namespaces into the regular collections and the
+// other ones, which are views or time-series collections. Namespaces without a collection are skipped.
+func splitRegularCollections(ctx context.Context, client *mongo.Client, collections []string) (regular, others []string, err error) {
+	onlyCollectionsNamespaces, err := listAllCollections(ctx, client, nil, nil, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	namespaces := make(map[string]struct{})
 	for db, collections := range onlyCollectionsNamespaces {
 		for _, collection := range removeEmptyStrings(collections) {
@@ -172,20 +198,22 @@ func checkNamespacesForViews(ctx context.Context, client *mongo.Client, collecti
 		}
 	}
 
-	filteredCollections := []string{}
+	regular = []string{}
 	for _, collection := range removeEmptyStrings(collections) {
 		if len(strings.Split(collection, ".")) < 2 { //nolint:gomnd
 			continue
 		}
 
 		if _, ok := namespaces[collection]; !ok {
-			return nil, errors.Errorf("namespace %s is a view and cannot be used for collstats/indexstats", collection)
+			others = append(others, collection)
+
+			continue
 		}
 
-		filteredCollections = append(filteredCollections, collection)
+		regular = append(regular, collection)
 	}
 
-	return filteredCollections, nil
+	return regular, others, nil
 }
 
 func listAllCollections(ctx context.Context, client *mongo.Client, filterInNamespaces []string, excludeDBs []string, skipViews bool) (map[string][]string, error) {
@@ -235,6 +263,49 @@ func listAllCollections(ctx context.Context, client *mongo.Client, filterInNames
 	return namespaces, nil
 }
 
+// listTimeseriesCollections returns the time-series namespaces matching filterInNamespaces, the same
+// way as listAllCollections. Time-series collections are not included by listCollections when views
+// are skipped because their type is "timeseries" instead of "collection".
+func listTimeseriesCollections(ctx context.Context, client *mongo.Client, filterInNamespaces []string, excludeDBs []string) ([]string, error) {
+	dbs, err := databases(ctx, client, filterInNamespaces, excludeDBs)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot make the list of databases to list time-series collections")
+	}
+
+	filterNS := removeEmptyStrings(filterInNamespaces)
+
+	// If there are no specified namespaces to search for collections, it means all dbs should be included.
+	if len(filterNS) == 0 {
+		filterNS = append(filterNS, dbs...)
+	}
+
+	var namespaces []string
+	for _, db := range dbs {
+		for _, namespace := range filterNS {
+			parts := strings.Split(namespace, ".")
+			dbname := strings.TrimSpace(parts[0])
+
+			if dbname == "" || dbname != db {
+				continue
+			}
+
+			colls, err := listCollectionsOfType(ctx, client, db, []string{namespace}, "timeseries")
+			if err != nil {
+				return nil, errors.Wrapf(err, "cannot list the time-series collections for %q", db)
+			}
+
+			for _, coll := range colls {
+				namespaces = append(namespaces, db+"."+coll)
+			}
+		}
+	}
+
+	namespaces = unique(namespaces)
+	sort.Strings(namespaces)
+
+	return namespaces, nil
+}
+
 func nonSystemCollectionsCount(ctx context.Context, client *mongo.Client, includeNamespaces []string, filterInCollections []string) (int, error) {
 	databases, err := databases(ctx, client, includeNamespaces, systemDBs)
 	if err != nil {
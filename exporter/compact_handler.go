@@ -0,0 +1,65 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"bufio"
+	"bytes"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+	"github.com/sirupsen/logrus"
+)
+
+// compactHandler serves the metrics in the text format without the HELP and TYPE comments.
+// With tens of thousands of series they are a big part of the response and Prometheus doesn't
+// need them. Like the default handler, the metrics gathered are served even if there are errors.
+func compactHandler(g prometheus.Gatherer, log *logrus.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mfs, err := g.Gather()
+		if err != nil {
+			log.Errorf("error gathering metrics: %s", err)
+		}
+
+		var buf bytes.Buffer
+		for _, mf := range mfs {
+			mf.Help = nil // no HELP line is written without help.
+			if _, err := expfmt.MetricFamilyToText(&buf, mf); err != nil {
+				log.Errorf("error encoding metric family %s: %s", mf.GetName(), err)
+			}
+		}
+
+		w.Header().Set("Content-Type", string(expfmt.FmtText))
+
+		// The lines are not read with a bufio.Scanner since it fails on lines longer than its
+		// buffer, like the ones of series with many long label values.
+		bw := bufio.NewWriter(w)
+		for rest := buf.Bytes(); len(rest) > 0; {
+			var line []byte
+			line, rest, _ = bytes.Cut(rest, []byte("\n"))
+			if bytes.HasPrefix(line, []byte("# TYPE ")) {
+				continue
+			}
+			_, _ = bw.Write(line)
+			_ = bw.WriteByte('\n')
+		}
+
+		if err := bw.Flush(); err != nil {
+			log.Errorf("error writing response: %s", err)
+		}
+	})
+}
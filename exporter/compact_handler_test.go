@@ -0,0 +1,73 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompactHandler(t *testing.T) {
+	upDesc := prometheus.NewDesc("mongodb_up", "Whether MongoDB is up.", nil, nil)
+	opDesc := prometheus.NewDesc("mongodb_ss_opcounters", "serverStatus.opcounters.", []string{"legacy_op_type"}, nil)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(metricsCollector{
+		prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 1),
+		prometheus.MustNewConstMetric(opDesc, prometheus.UntypedValue, 4, "insert"),
+		prometheus.MustNewConstMetric(opDesc, prometheus.UntypedValue, 2118, "query"),
+	})
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	compactHandler(registry, logrus.New()).ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain"))
+
+	body := rec.Body.String()
+	assert.NotContains(t, body, "# HELP")
+	assert.NotContains(t, body, "# TYPE")
+
+	want := `mongodb_ss_opcounters{legacy_op_type="insert"} 4
+mongodb_ss_opcounters{legacy_op_type="query"} 2118
+mongodb_up 1
+`
+	assert.Equal(t, want, body)
+}
+
+func TestCompactHandlerLongLines(t *testing.T) {
+	// Longer than the 64 KiB buffer of a bufio.Scanner.
+	query := strings.Repeat("x", 100*1024)
+	desc := prometheus.NewDesc("mongodb_query_count", "Count of the query.", []string{"query"}, nil)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(metricsCollector{
+		prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 1, query),
+	})
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	compactHandler(registry, logrus.New()).ServeHTTP(rec, req)
+
+	assert.Equal(t, `mongodb_query_count{query="`+query+`"} 1`+"\n", rec.Body.String())
+}
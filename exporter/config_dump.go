@@ -0,0 +1,144 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// redacted replaces the secrets in the configuration dump.
+const redacted = "<redacted>"
+
+// DumpConfig returns the exporter options, one per line, with the URI placeholders expanded and the
+// secrets redacted: the passwords of the URIs and of the Pushgateway URL, the Atlas API private key
+// and the secret options, like authMechanismProperties or those whose name contains "password",
+// "token" or "secret".
+// It is meant to check the configuration resulting from the flags and the environment, so it
+// doesn't need an Exporter: nothing is started.
+func DumpConfig(opts *Opts) string {
+	var b strings.Builder
+
+	v := reflect.ValueOf(*opts)
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Name
+
+		var value interface{}
+		switch name {
+		case "Logger":
+			continue
+		case "URI", "ConfigServerURI":
+			uri := v.Field(i).String()
+			if expanded, err := expandURI(uri); err == nil {
+				uri = expanded
+			}
+			value = redactURI(uri)
+		case "PushGatewayURL":
+			value = redactURI(opts.PushGatewayURL)
+		case "AtlasAPIPrivateKey":
+			value = ""
+			if opts.AtlasAPIPrivateKey != "" {
+				value = redacted
+			}
+		case "DriverOptions":
+			value = redactOptions(opts.DriverOptions)
+		default:
+			value = v.Field(i).Interface()
+		}
+
+		fmt.Fprintf(&b, "%s: %v\n", name, value)
+	}
+
+	return b.String()
+}
+
+// redactURI hides the password of a MongoDB URI and the values of the secret query options, see
+// isSecretOption. The user name is kept.
+func redactURI(uri string) string {
+	scheme, rest, ok := strings.Cut(uri, "://")
+	if !ok {
+		scheme, rest = "", uri
+	} else {
+		scheme += "://"
+	}
+
+	var userinfo string
+	if user, hosts, ok := cutUserinfo(rest); ok {
+		userinfo = user + "@"
+		if name, _, ok := strings.Cut(user, ":"); ok {
+			userinfo = name + ":" + redacted + "@"
+		}
+		rest = hosts
+	}
+
+	rest, query, hasQuery := strings.Cut(rest, "?")
+	if !hasQuery {
+		return scheme + userinfo + rest
+	}
+
+	params := strings.Split(query, "&")
+	for i, param := range params {
+		if key, _, ok := strings.Cut(param, "="); ok && isSecretOption(key) {
+			params[i] = key + "=" + redacted
+		}
+	}
+
+	return scheme + userinfo + rest + "?" + strings.Join(params, "&")
+}
+
+// cutUserinfo splits a MongoDB URI without its scheme around the @ that ends the userinfo.
+// Unescaped passwords could contain an @ or a ? but not a /, so it is the last @ before the
+// first /.
+func cutUserinfo(s string) (userinfo, rest string, found bool) {
+	authority := s
+	if i := strings.Index(s, "/"); i >= 0 {
+		authority = s[:i]
+	}
+
+	at := strings.LastIndex(authority, "@")
+	if at < 0 {
+		return "", s, false
+	}
+
+	return s[:at], s[at+1:], true
+}
+
+// redactOptions returns a copy of the options with the values of the secret ones redacted.
+func redactOptions(opts map[string]string) map[string]string {
+	if opts == nil {
+		return nil
+	}
+
+	res := make(map[string]string, len(opts))
+	for k, v := range opts {
+		if isSecretOption(k) {
+			v = redacted
+		}
+		res[k] = v
+	}
+
+	return res
+}
+
+// isSecretOption tells if the value of the URI or driver option name is a secret, like
+// tlsCertificateKeyFilePassword or the AWS session token of authMechanismProperties.
+func isSecretOption(name string) bool {
+	name = strings.ToLower(name)
+
+	return name == "authmechanismproperties" ||
+		strings.Contains(name, "password") || strings.Contains(name, "token") || strings.Contains(name, "secret")
+}
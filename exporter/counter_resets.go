@@ -0,0 +1,148 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// monotonicCounters are the metrics exposed as counters corrected for server restarts when
+// Opts.MonotonicCounters is enabled.
+var monotonicCounters = map[string]bool{
+	"mongodb_ss_opcounters":                      true,
+	"mongodb_ss_opcountersRepl":                  true,
+	"mongodb_ss_network_bytesIn":                 true,
+	"mongodb_ss_network_bytesOut":                true,
+	"mongodb_ss_network_numRequests":             true,
+	"mongodb_op_counters_total":                  true,
+	"mongodb_mongod_op_counters_repl_total":      true,
+	"mongodb_network_bytes_total":                true,
+	"mongodb_network_metrics_num_requests_total": true,
+	"mongodb_asserts_total":                      true,
+}
+
+// counterResets keeps, for every target, the last values of the monotonic counters and the
+// offsets added to them after the server restarted.
+type counterResets struct {
+	lock    sync.Mutex
+	targets map[string]*counterState
+}
+
+type counterState struct {
+	uptimeMillis float64
+	last         map[string]float64
+	offset       map[string]float64
+}
+
+func newCounterResets() *counterResets {
+	return &counterResets{targets: make(map[string]*counterState)}
+}
+
+// gatherer returns a gatherer exposing the monotonic counters of g, scraped from target, as
+// counters that don't go back to zero when the server restarts. Each value is increased by the
+// values the counter had before the restarts so rate() doesn't see a reset.
+func (c *counterResets) gatherer(g prometheus.Gatherer, target string) prometheus.Gatherer {
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		mfs, err := g.Gather()
+
+		c.lock.Lock()
+		defer c.lock.Unlock()
+
+		state, ok := c.targets[target]
+		if !ok {
+			state = &counterState{last: make(map[string]float64), offset: make(map[string]float64)}
+			c.targets[target] = state
+		}
+
+		// A counter can be higher than its previous value even after a restart, so the uptime
+		// is the reliable way to detect it. Decreasing values are also handled in case the
+		// uptime is not collected.
+		uptime, hasUptime := uptimeMillis(mfs)
+		restarted := hasUptime && uptime < state.uptimeMillis
+		if hasUptime {
+			state.uptimeMillis = uptime
+		}
+
+		counterType := dto.MetricType_COUNTER
+		for _, mf := range mfs {
+			if !monotonicCounters[mf.GetName()] {
+				continue
+			}
+
+			mf.Type = &counterType
+			for _, m := range mf.GetMetric() {
+				key := metricKey(mf.GetName(), m)
+				v := metricValue(m)
+
+				if last, seen := state.last[key]; seen && (restarted || v < last) {
+					state.offset[key] += last
+				}
+				state.last[key] = v
+
+				value := v + state.offset[key]
+				m.Counter = &dto.Counter{Value: &value}
+				m.Gauge = nil
+				m.Untyped = nil
+			}
+		}
+
+		return mfs, err
+	})
+}
+
+// uptimeMillis returns the server uptime from the gathered metrics.
+func uptimeMillis(mfs []*dto.MetricFamily) (float64, bool) {
+	for _, mf := range mfs {
+		if len(mf.GetMetric()) == 0 {
+			continue
+		}
+
+		switch mf.GetName() {
+		case "mongodb_ss_uptimeMillis":
+			return metricValue(mf.GetMetric()[0]), true
+		case "mongodb_ss_uptime", "mongodb_instance_uptime_seconds":
+			return metricValue(mf.GetMetric()[0]) * 1000, true //nolint:gomnd
+		}
+	}
+
+	return 0, false
+}
+
+func metricValue(m *dto.Metric) float64 {
+	switch {
+	case m.GetCounter() != nil:
+		return m.GetCounter().GetValue()
+	case m.GetGauge() != nil:
+		return m.GetGauge().GetValue()
+	default:
+		return m.GetUntyped().GetValue()
+	}
+}
+
+func metricKey(name string, m *dto.Metric) string {
+	labels := make([]string, 0, len(m.GetLabel()))
+	for _, l := range m.GetLabel() {
+		labels = append(labels, l.GetName()+"="+l.GetValue())
+	}
+	sort.Strings(labels)
+
+	return name + "{" + strings.Join(labels, ",") + "}"
+}
@@ -0,0 +1,74 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCounterResets(t *testing.T) {
+	uptimeDesc := prometheus.NewDesc("mongodb_ss_uptimeMillis", "serverStatus.uptimeMillis", nil, nil)
+	opDesc := prometheus.NewDesc("mongodb_ss_opcounters", "serverStatus.opcounters.", []string{"legacy_op_type"}, nil)
+
+	resets := newCounterResets()
+
+	scrapes := []struct {
+		uptime  float64
+		inserts float64
+		want    float64
+	}{
+		{uptime: 1000, inserts: 100, want: 100},
+		{uptime: 2000, inserts: 150, want: 150},
+		// The server restarted, the counter starts from 0 again.
+		{uptime: 500, inserts: 20, want: 170},
+		{uptime: 1500, inserts: 200, want: 350},
+		// Restarted and the counter is already higher than before the restart.
+		{uptime: 100, inserts: 250, want: 600},
+	}
+
+	var previous float64
+	for i, s := range scrapes {
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(metricsCollector{
+			prometheus.MustNewConstMetric(uptimeDesc, prometheus.UntypedValue, s.uptime),
+			prometheus.MustNewConstMetric(opDesc, prometheus.UntypedValue, s.inserts, "insert"),
+		})
+
+		mfs, err := resets.gatherer(registry, "mongodb://127.0.0.1:27017").Gather()
+		require.NoError(t, err)
+
+		var got *dto.MetricFamily
+		for _, mf := range mfs {
+			if mf.GetName() == "mongodb_ss_opcounters" {
+				got = mf
+			}
+		}
+		require.NotNil(t, got)
+
+		assert.Equal(t, dto.MetricType_COUNTER, got.GetType())
+		value := got.GetMetric()[0].GetCounter().GetValue()
+		assert.Equal(t, s.want, value, "scrape %d", i)
+
+		// rate() must never see a decrease.
+		assert.GreaterOrEqual(t, value, previous, "scrape %d", i)
+		previous = value
+	}
+}
@@ -0,0 +1,106 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type cursorCollector struct {
+	ctx  context.Context
+	base *baseCollector
+}
+
+// newCursorCollector creates a collector for the cursor statistics reported by serverStatus.
+func newCursorCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger) *cursorCollector {
+	return &cursorCollector{
+		ctx:  ctx,
+		base: newBaseCollector(client, logger),
+	}
+}
+
+func (d *cursorCollector) Describe(ch chan<- *prometheus.Desc) {
+	d.base.Describe(d.ctx, ch, d.collect)
+}
+
+func (d *cursorCollector) Collect(ch chan<- prometheus.Metric) {
+	d.base.Collect(ch)
+}
+
+func (d *cursorCollector) collect(ch chan<- prometheus.Metric) {
+	defer measureCollectTime(ch, "mongodb", "cursor")()
+
+	logger := d.base.logger
+
+	m, err := serverStatus(d.ctx, d.base.client)
+	if err != nil {
+		logger.Errorf("cannot get cursor stats: %s", err)
+
+		return
+	}
+
+	for _, metric := range cursorMetrics(m) {
+		ch <- metric
+	}
+}
+
+// cursorMetrics returns the open and timed out cursor metrics from a serverStatus document.
+// Recent versions report them in metrics.cursor while old versions have a top level cursors
+// section using different field names.
+func cursorMetrics(m bson.M) []prometheus.Metric {
+	var timedOut interface{}
+	open := make(map[string]interface{})
+
+	if cursor, ok := walkTo(m, []string{"metrics", "cursor"}).(bson.M); ok {
+		timedOut = cursor["timedOut"]
+		if o, ok := cursor["open"].(bson.M); ok {
+			for _, t := range []string{"total", "pinned", "noTimeout"} {
+				open[t] = o[t]
+			}
+		}
+	} else if cursors, ok := m["cursors"].(bson.M); ok {
+		timedOut = cursors["timedOut"]
+		open["total"] = cursors["totalOpen"]
+		open["pinned"] = cursors["pinned"]
+		open["noTimeout"] = cursors["totalNoTimeout"]
+	}
+
+	var metrics []prometheus.Metric
+
+	d := prometheus.NewDesc("mongodb_cursors_open", "Number of cursors currently open.", []string{"type"}, nil)
+	for _, t := range []string{"total", "pinned", "noTimeout"} {
+		f, err := asFloat64(open[t])
+		if err != nil || f == nil {
+			continue
+		}
+		metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.GaugeValue, *f, t))
+	}
+
+	if f, err := asFloat64(timedOut); err == nil && f != nil {
+		d := prometheus.NewDesc("mongodb_cursors_timed_out_total",
+			"Number of cursors that timed out since the server started.", nil, nil)
+		metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.CounterValue, *f))
+	}
+
+	return metrics
+}
+
+var _ prometheus.Collector = (*cursorCollector)(nil)
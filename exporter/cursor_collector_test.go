@@ -0,0 +1,81 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestCursorMetrics(t *testing.T) {
+	expected := `
+	# HELP mongodb_cursors_open Number of cursors currently open.
+	# TYPE mongodb_cursors_open gauge
+	mongodb_cursors_open{type="noTimeout"} 1
+	mongodb_cursors_open{type="pinned"} 2
+	mongodb_cursors_open{type="total"} 5
+	# HELP mongodb_cursors_timed_out_total Number of cursors that timed out since the server started.
+	# TYPE mongodb_cursors_timed_out_total counter
+	mongodb_cursors_timed_out_total 3` + "\n"
+
+	tests := []struct {
+		name string
+		m    bson.M
+	}{
+		{
+			name: "metrics.cursor",
+			m: bson.M{
+				"metrics": bson.M{
+					"cursor": bson.M{
+						"timedOut": int64(3),
+						"open": bson.M{
+							"noTimeout":    int64(1),
+							"pinned":       int64(2),
+							"total":        int64(5),
+							"singleTarget": int64(0),
+							"multiTarget":  int64(0),
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "old cursors section",
+			m: bson.M{
+				"cursors": bson.M{
+					"totalOpen":      int32(5),
+					"pinned":         int32(2),
+					"totalNoTimeout": int32(1),
+					"timedOut":       int32(3),
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			err := testutil.CollectAndCompare(metricsCollector(cursorMetrics(tt.m)), strings.NewReader(expected))
+			assert.NoError(t, err)
+		})
+	}
+
+	assert.Empty(t, cursorMetrics(bson.M{"ok": float64(1)}))
+}
@@ -0,0 +1,268 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultCustomQueryTimeout bounds a custom query without a timeout of its own.
+const defaultCustomQueryTimeout = 5 * time.Second
+
+// customQuery is an aggregation pipeline of Opts.CustomQueryFile, exposed as mongodb_custom_<name>.
+type customQuery struct {
+	Name string `yaml:"-"`
+	// Namespace is the db.collection the pipeline runs against.
+	Namespace string `yaml:"namespace"`
+	// Pipeline is the aggregation pipeline in MongoDB Extended JSON, like
+	// [{"$match": {"status": "pending"}}, {"$count": "count"}].
+	Pipeline string `yaml:"pipeline"`
+	// Value is the numeric field of the result documents exposed as the gauge value.
+	Value string `yaml:"value"`
+	// Labels are the fields of the result documents exposed as labels.
+	Labels  []string      `yaml:"labels"`
+	Help    string        `yaml:"help"`
+	Timeout time.Duration `yaml:"timeout"`
+
+	stages bson.A
+}
+
+// topologyLabels are the labels added to the custom query metrics from the topology info.
+var topologyLabelNames = map[string]struct{}{ //nolint:gochecknoglobals
+	labelClusterRole:     {},
+	labelClusterID:       {},
+	labelReplicasetName:  {},
+	labelReplicasetState: {},
+}
+
+// aggregateFunc runs the pipeline of the custom query and returns the result documents.
+type aggregateFunc func(ctx context.Context, q *customQuery) ([]bson.M, error)
+
+// loadCustomQueries reads the YAML file mapping the query names to the queries, sorted by name.
+func loadCustomQueries(path string) ([]*customQuery, error) {
+	data, err := os.ReadFile(path) //nolint:gosec
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read the custom query file")
+	}
+
+	var byName map[string]*customQuery
+	if err := yaml.Unmarshal(data, &byName); err != nil {
+		return nil, errors.Wrapf(err, "invalid custom query file %s", path)
+	}
+
+	queries := make([]*customQuery, 0, len(byName))
+	for name, q := range byName {
+		if q == nil {
+			return nil, errors.Errorf("custom query %q is empty", name)
+		}
+
+		q.Name = name
+		if err := q.validate(); err != nil {
+			return nil, errors.Wrapf(err, "invalid custom query %q", name)
+		}
+
+		queries = append(queries, q)
+	}
+
+	sort.Slice(queries, func(i, j int) bool { return queries[i].Name < queries[j].Name })
+
+	return queries, nil
+}
+
+// validate checks the query and parses its pipeline.
+func (q *customQuery) validate() error {
+	if !model.IsValidMetricName(model.LabelValue(q.metricName())) {
+		return errors.New("the name cannot be used in a metric name")
+	}
+
+	if db, coll := splitNamespace(q.Namespace); db == "" || coll == "" {
+		return errors.Errorf("the namespace %q is not db.collection", q.Namespace)
+	}
+
+	if q.Value == "" {
+		return errors.New("the value field is missing")
+	}
+
+	seen := make(map[string]bool, len(q.Labels))
+	for _, label := range q.Labels {
+		if !model.LabelName(label).IsValid() || strings.HasPrefix(label, model.ReservedLabelPrefix) {
+			return errors.Errorf("the field %q cannot be used as a label name", label)
+		}
+
+		if _, ok := topologyLabelNames[label]; ok {
+			return errors.Errorf("the label %q is a topology label", label)
+		}
+
+		if seen[label] {
+			return errors.Errorf("the label %q is repeated", label)
+		}
+		seen[label] = true
+	}
+
+	if err := bson.UnmarshalExtJSON([]byte(q.Pipeline), false, &q.stages); err != nil {
+		return errors.Wrap(err, "invalid pipeline")
+	}
+
+	if q.Timeout <= 0 {
+		q.Timeout = defaultCustomQueryTimeout
+	}
+
+	return nil
+}
+
+func (q *customQuery) metricName() string {
+	return "mongodb_custom_" + q.Name
+}
+
+type customQueryCollector struct {
+	ctx          context.Context
+	base         *baseCollector
+	topologyInfo labelsGetter
+	queries      []*customQuery
+	aggregate    aggregateFunc
+}
+
+// newCustomQueryCollector creates a collector running the custom queries on every scrape.
+func newCustomQueryCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger, topology labelsGetter, queries []*customQuery) *customQueryCollector {
+	return &customQueryCollector{
+		ctx:          ctx,
+		base:         newBaseCollector(client, logger),
+		topologyInfo: topology,
+		queries:      queries,
+		aggregate: func(ctx context.Context, q *customQuery) ([]bson.M, error) {
+			return runCustomQuery(ctx, client, q)
+		},
+	}
+}
+
+func (d *customQueryCollector) Describe(ch chan<- *prometheus.Desc) {
+	d.base.Describe(d.ctx, ch, d.collect)
+}
+
+func (d *customQueryCollector) Collect(ch chan<- prometheus.Metric) {
+	d.base.Collect(ch)
+}
+
+func (d *customQueryCollector) collect(ch chan<- prometheus.Metric) {
+	defer measureCollectTime(ch, "mongodb", "customqueries")()
+
+	for _, metric := range customQueryMetrics(d.ctx, d.queries, d.aggregate, d.topologyInfo.baseLabels(), d.base.logger) {
+		ch <- metric
+	}
+}
+
+func runCustomQuery(ctx context.Context, client *mongo.Client, q *customQuery) ([]bson.M, error) {
+	db, coll := splitNamespace(q.Namespace)
+
+	cursor, err := client.Database(db).Collection(coll).Aggregate(ctx, q.stages)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot run the pipeline")
+	}
+
+	var docs []bson.M
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, errors.Wrap(err, "cannot read the pipeline results")
+	}
+
+	return docs, nil
+}
+
+// customQueryMetrics runs the queries, each one with its own timeout, and returns a gauge for every
+// result document, with the topology labels. A failing query is logged and skipped so it doesn't
+// fail the other ones. So is a query returning several documents with the same label values, since
+// their samples would be duplicated series.
+func customQueryMetrics(ctx context.Context, queries []*customQuery, aggregate aggregateFunc, topologyLabels map[string]string, logger *logrus.Logger) []prometheus.Metric {
+	var metrics []prometheus.Metric
+
+	for _, q := range queries {
+		qCtx, cancel := context.WithTimeout(ctx, q.Timeout)
+		docs, err := aggregate(qCtx, q)
+		cancel()
+
+		if err != nil {
+			logger.Errorf("cannot run the custom query %q: %s", q.Name, err)
+
+			continue
+		}
+
+		metrics = append(metrics, q.metrics(docs, topologyLabels, logger)...)
+	}
+
+	return metrics
+}
+
+// metrics returns the gauges of the result documents of the query or nil if two documents have
+// the same label values.
+func (q *customQuery) metrics(docs []bson.M, topologyLabels map[string]string, logger *logrus.Logger) []prometheus.Metric {
+	help := q.Help
+	if help == "" {
+		help = fmt.Sprintf("Custom query %s on %s.", q.Name, q.Namespace)
+	}
+	d := prometheus.NewDesc(q.metricName(), help, q.Labels, topologyLabels)
+
+	metrics := make([]prometheus.Metric, 0, len(docs))
+	seen := make(map[string]bool, len(docs))
+
+	for _, doc := range docs {
+		value, err := asFloat64(doc[q.Value])
+		if err != nil || value == nil {
+			logger.Errorf("the custom query %q returned no numeric %q field", q.Name, q.Value)
+
+			continue
+		}
+
+		labels := make([]string, len(q.Labels))
+		for i, label := range q.Labels {
+			if v, ok := doc[label]; ok {
+				labels[i] = fmt.Sprint(v)
+			}
+		}
+
+		key := strings.Join(labels, "\xff")
+		if seen[key] {
+			logger.Errorf("the custom query %q returned several documents with the labels %q, add labels to tell them apart", q.Name, labels)
+
+			return nil
+		}
+		seen[key] = true
+
+		metric, err := prometheus.NewConstMetric(d, prometheus.GaugeValue, *value, labels...)
+		if err != nil {
+			logger.Errorf("cannot create the metric of the custom query %q: %s", q.Name, err)
+
+			continue
+		}
+
+		metrics = append(metrics, metric)
+	}
+
+	return metrics
+}
+
+var _ prometheus.Collector = (*customQueryCollector)(nil)
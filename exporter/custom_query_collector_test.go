@@ -0,0 +1,126 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestLoadCustomQueries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queries.yml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+pending_orders:
+  namespace: shop.orders
+  pipeline: '[{"$match": {"status": "pending"}}, {"$group": {"_id": "$region", "count": {"$sum": 1}}}]'
+  value: count
+  labels: [_id]
+  help: Number of orders waiting to be shipped.
+  timeout: 2s
+active_users:
+  namespace: app.users
+  pipeline: '[{"$match": {"active": true}}, {"$count": "n"}]'
+  value: n
+`), 0o600))
+
+	queries, err := loadCustomQueries(path)
+	require.NoError(t, err)
+	require.Len(t, queries, 2)
+
+	assert.Equal(t, "active_users", queries[0].Name)
+	assert.Equal(t, defaultCustomQueryTimeout, queries[0].Timeout)
+	assert.Equal(t, bson.A{
+		bson.D{{Key: "$match", Value: bson.D{{Key: "active", Value: true}}}},
+		bson.D{{Key: "$count", Value: "n"}},
+	}, queries[0].stages)
+
+	assert.Equal(t, "pending_orders", queries[1].Name)
+	assert.Equal(t, 2*time.Second, queries[1].Timeout)
+	assert.Equal(t, []string{"_id"}, queries[1].Labels)
+
+	for name, query := range map[string]string{
+		"bad-name":  "{namespace: db.c, pipeline: '[]', value: n}",
+		"no_coll":   "{namespace: db, pipeline: '[]', value: n}",
+		"no_value":  "{namespace: db.c, pipeline: '[]'}",
+		"bad_pipe":  "{namespace: db.c, pipeline: '[{$match', value: n}",
+		"bad_label": "{namespace: db.c, pipeline: '[]', value: n, labels: [a-b]}",
+		"dup_label": "{namespace: db.c, pipeline: '[]', value: n, labels: [a, b, a]}",
+		"reserved":  "{namespace: db.c, pipeline: '[]', value: n, labels: [__name__]}",
+		"topology":  "{namespace: db.c, pipeline: '[]', value: n, labels: [rs_nm]}",
+	} {
+		require.NoError(t, os.WriteFile(path, []byte(name+": "+query), 0o600))
+		_, err := loadCustomQueries(path)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestCustomQueryMetrics(t *testing.T) {
+	queries := []*customQuery{
+		{Name: "active_users", Namespace: "app.users", Value: "n", Timeout: time.Second},
+		{Name: "failing", Namespace: "app.broken", Value: "n", Timeout: time.Second},
+		// Several documents without labels would be duplicated series.
+		{Name: "colliding", Namespace: "app.events", Value: "n", Timeout: time.Second},
+		{
+			Name: "pending_orders", Namespace: "shop.orders", Value: "count", Labels: []string{"region"},
+			Help: "Number of orders waiting to be shipped.", Timeout: time.Second,
+		},
+	}
+
+	aggregate := func(ctx context.Context, q *customQuery) ([]bson.M, error) {
+		_, ok := ctx.Deadline()
+		assert.True(t, ok, "the query has no timeout")
+
+		switch q.Namespace {
+		case "app.users":
+			return []bson.M{{"n": int32(42)}}, nil
+		case "app.events":
+			return []bson.M{{"n": int32(1)}, {"n": int32(2)}}, nil
+		case "shop.orders":
+			return []bson.M{
+				{"_id": "eu", "region": "eu", "count": int64(7)},
+				{"_id": "us", "region": "us", "count": int64(3)},
+				// Invalid label values are skipped instead of panicking.
+				{"_id": "xx", "region": "\xff", "count": int64(1)},
+			}, nil
+		}
+
+		return nil, errors.New("ns not found")
+	}
+
+	expected := `
+	# HELP mongodb_custom_active_users Custom query active_users on app.users.
+	# TYPE mongodb_custom_active_users gauge
+	mongodb_custom_active_users{rs_nm="rs1"} 42
+	# HELP mongodb_custom_pending_orders Number of orders waiting to be shipped.
+	# TYPE mongodb_custom_pending_orders gauge
+	mongodb_custom_pending_orders{region="eu",rs_nm="rs1"} 7
+	mongodb_custom_pending_orders{region="us",rs_nm="rs1"} 3` + "\n"
+
+	metrics := customQueryMetrics(context.Background(), queries, aggregate, map[string]string{"rs_nm": "rs1"}, logrus.New())
+	err := testutil.CollectAndCompare(metricsCollector(metrics), strings.NewReader(expected))
+	assert.NoError(t, err)
+}
@@ -34,6 +34,11 @@ type dbstatsCollector struct {
 	databaseFilter []string
 
 	freeStorage bool
+
+	// instanceTotals exposes the number of objects and the data size summed across the databases,
+	// except the totalsExclude ones.
+	instanceTotals bool
+	totalsExclude  []string
 }
 
 // newDBStatsCollector creates a collector for statistics on database storage.
@@ -65,13 +70,15 @@ func (d *dbstatsCollector) collect(ch chan<- prometheus.Metric) {
 	logger := d.base.logger
 	client := d.base.client
 
-	dbNames, err := databases(d.ctx, client, d.databaseFilter, nil)
+	dbNames, err := cachedDatabases(d.ctx, client, d.databaseFilter, nil)
 	if err != nil {
 		logger.Errorf("Failed to get database names: %s", err)
 
 		return
 	}
 
+	stats := make(map[string]bson.M, len(dbNames))
+
 	logger.Debugf("getting stats for databases: %v", dbNames)
 	for _, db := range dbNames {
 		var dbStats bson.M
@@ -104,7 +111,108 @@ func (d *dbstatsCollector) collect(ch chan<- prometheus.Metric) {
 		for _, metric := range newMetrics {
 			ch <- metric
 		}
+
+		for _, metric := range databaseSizeMetrics(dbStats, labels) {
+			ch <- metric
+		}
+
+		stats[db] = dbStats
+	}
+
+	if d.instanceTotals {
+		for _, metric := range instanceTotalsMetrics(stats, d.totalsExclude, d.topologyInfo.baseLabels()) {
+			ch <- metric
+		}
 	}
 }
 
+// instanceTotalsMetrics returns the number of objects and the data size summed across the dbStats
+// responses of the databases, by name, except the excluded ones. The system databases are excluded
+// if exclude is nil.
+func instanceTotalsMetrics(stats map[string]bson.M, exclude []string, labels map[string]string) []prometheus.Metric {
+	if exclude == nil {
+		exclude = systemDBs
+	}
+
+	excluded := make(map[string]bool, len(exclude))
+	for _, db := range exclude {
+		excluded[db] = true
+	}
+
+	var objects, dataSize float64
+	for db, dbStats := range stats {
+		if excluded[db] {
+			continue
+		}
+
+		if f, err := asFloat64(dbStats["objects"]); err == nil && f != nil {
+			objects += *f
+		}
+
+		if f, err := asFloat64(dbStats["dataSize"]); err == nil && f != nil {
+			dataSize += *f
+		}
+	}
+
+	return []prometheus.Metric{
+		prometheus.MustNewConstMetric(prometheus.NewDesc("mongodb_instance_objects_total",
+			"Number of documents in the databases of the instance.", nil, labels),
+			prometheus.GaugeValue, objects),
+		prometheus.MustNewConstMetric(prometheus.NewDesc("mongodb_instance_data_size_bytes",
+			"Uncompressed size of the documents in the databases of the instance.", nil, labels),
+			prometheus.GaugeValue, dataSize),
+	}
+}
+
+// databaseSizeMetrics returns the size of the data, the indexes and the storage of a database and
+// the usage of the file system holding it from a dbStats response. The file system sizes are only
+// reported by recent servers. The fields missing in the response are skipped.
+func databaseSizeMetrics(dbStats bson.M, labels map[string]string) []prometheus.Metric {
+	sizes := []struct {
+		field string
+		name  string
+		help  string
+	}{
+		{
+			field: "dataSize",
+			name:  "mongodb_database_data_size_bytes",
+			help:  "Uncompressed size of the documents in the database.",
+		},
+		{
+			field: "indexSize",
+			name:  "mongodb_database_index_size_bytes",
+			help:  "Size of the indexes in the database.",
+		},
+		{
+			field: "storageSize",
+			name:  "mongodb_database_storage_size_bytes",
+			help:  "Storage allocated to the collections of the database, including free space.",
+		},
+		{
+			field: "fsUsedSize",
+			name:  "mongodb_database_fs_used_bytes",
+			help:  "Used space of the file system holding the database.",
+		},
+		{
+			field: "fsTotalSize",
+			name:  "mongodb_database_fs_total_bytes",
+			help:  "Total size of the file system holding the database.",
+		},
+	}
+
+	var metrics []prometheus.Metric
+
+	for _, size := range sizes {
+		f, err := asFloat64(dbStats[size.field])
+		if err != nil || f == nil {
+			continue
+		}
+
+		d := prometheus.NewDesc(size.name, size.help, nil, labels)
+		metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.GaugeValue, *f))
+	}
+
+	return metrics
+}
+
 var _ prometheus.Collector = (*dbstatsCollector)(nil)
@@ -79,3 +79,75 @@ func TestDBStatsCollector(t *testing.T) {
 	err := testutil.CollectAndCompare(c, expected, filters...)
 	assert.NoError(t, err)
 }
+
+func TestDatabaseSizeMetrics(t *testing.T) {
+	dbStats := bson.M{
+		"db":          "testdb",
+		"collections": int32(3),
+		"objects":     int64(3000),
+		"dataSize":    float64(1048576),
+		"storageSize": float64(3145728),
+		"indexSize":   float64(524288),
+		"fsUsedSize":  float64(21474836480),
+		"fsTotalSize": float64(107374182400),
+		"scaleFactor": int32(1),
+		"ok":          float64(1),
+	}
+
+	expected := `
+	# HELP mongodb_database_data_size_bytes Uncompressed size of the documents in the database.
+	# TYPE mongodb_database_data_size_bytes gauge
+	mongodb_database_data_size_bytes{database="testdb"} 1.048576e+06
+	# HELP mongodb_database_fs_total_bytes Total size of the file system holding the database.
+	# TYPE mongodb_database_fs_total_bytes gauge
+	mongodb_database_fs_total_bytes{database="testdb"} 1.073741824e+11
+	# HELP mongodb_database_fs_used_bytes Used space of the file system holding the database.
+	# TYPE mongodb_database_fs_used_bytes gauge
+	mongodb_database_fs_used_bytes{database="testdb"} 2.147483648e+10
+	# HELP mongodb_database_index_size_bytes Size of the indexes in the database.
+	# TYPE mongodb_database_index_size_bytes gauge
+	mongodb_database_index_size_bytes{database="testdb"} 524288
+	# HELP mongodb_database_storage_size_bytes Storage allocated to the collections of the database, including free space.
+	# TYPE mongodb_database_storage_size_bytes gauge
+	mongodb_database_storage_size_bytes{database="testdb"} 3.145728e+06` + "\n"
+
+	labels := map[string]string{"database": "testdb"}
+	err := testutil.CollectAndCompare(metricsCollector(databaseSizeMetrics(dbStats, labels)), strings.NewReader(expected))
+	assert.NoError(t, err)
+
+	// Old servers don't report the file system sizes.
+	delete(dbStats, "fsUsedSize")
+	delete(dbStats, "fsTotalSize")
+	assert.Len(t, databaseSizeMetrics(dbStats, labels), 3)
+}
+
+func TestInstanceTotalsMetrics(t *testing.T) {
+	stats := map[string]bson.M{
+		"admin":  {"db": "admin", "objects": int64(5), "dataSize": float64(2048)},
+		"config": {"db": "config", "objects": int64(20), "dataSize": float64(8192)},
+		"local":  {"db": "local", "objects": int64(1000), "dataSize": float64(1048576)},
+		"shop":   {"db": "shop", "objects": int64(3000), "dataSize": float64(1048576)},
+		"crm":    {"db": "crm", "objects": int32(250), "dataSize": float64(65536)},
+	}
+
+	expected := `
+	# HELP mongodb_instance_data_size_bytes Uncompressed size of the documents in the databases of the instance.
+	# TYPE mongodb_instance_data_size_bytes gauge
+	mongodb_instance_data_size_bytes{rs_nm="rs0"} 1.114112e+06
+	# HELP mongodb_instance_objects_total Number of documents in the databases of the instance.
+	# TYPE mongodb_instance_objects_total gauge
+	mongodb_instance_objects_total{rs_nm="rs0"} 3250` + "\n"
+
+	labels := map[string]string{"rs_nm": "rs0"}
+	err := testutil.CollectAndCompare(metricsCollector(instanceTotalsMetrics(stats, nil, labels)), strings.NewReader(expected))
+	assert.NoError(t, err)
+
+	expected = `
+	# HELP mongodb_instance_objects_total Number of documents in the databases of the instance.
+	# TYPE mongodb_instance_objects_total gauge
+	mongodb_instance_objects_total{rs_nm="rs0"} 4270` + "\n"
+
+	err = testutil.CollectAndCompare(metricsCollector(instanceTotalsMetrics(stats, []string{"admin"}, labels)),
+		strings.NewReader(expected), "mongodb_instance_objects_total")
+	assert.NoError(t, err)
+}
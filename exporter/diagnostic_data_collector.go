@@ -17,6 +17,7 @@ package exporter
 
 import (
 	"context"
+	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
@@ -37,6 +38,8 @@ type diagnosticDataCollector struct {
 
 	compatibleMode bool
 	topologyInfo   labelsGetter
+	// paths, if not empty, restricts the metrics to the parts of the document under these dotted paths.
+	paths []string
 }
 
 // newDiagnosticDataCollector creates a collector for diagnostic information.
@@ -91,8 +94,13 @@ func (d *diagnosticDataCollector) collect(ch chan<- prometheus.Metric) {
 	logger.Debug("getDiagnosticData result")
 	debugResult(logger, m)
 
-	metrics := makeMetrics("", m, d.topologyInfo.baseLabels(), d.compatibleMode)
-	metrics = append(metrics, locksMetrics(logger, m)...)
+	flattened := m
+	if len(d.paths) > 0 {
+		flattened = filterDiagnosticData(m, d.paths)
+	}
+
+	metrics := makeMetrics("", flattened, d.topologyInfo.baseLabels(), d.compatibleMode)
+	metrics = append(metrics, locksMetrics(logger, flattened)...)
 
 	securityMetric, err := d.getSecurityMetricFromLineOptions(client)
 	if err != nil {
@@ -185,5 +193,67 @@ func (d *diagnosticDataCollector) retrieveSecurityEncryptionMetric(securityOptio
 	return metric, nil
 }
 
+// filterDiagnosticData returns the parts of the diagnostic data under the dotted paths. Since
+// most metrics come from the sections of the document, like serverStatus or replSetGetStatus,
+// a path is looked up both from the root and from every section: wiredTiger.cache selects
+// serverStatus.wiredTiger.cache.
+func filterDiagnosticData(m bson.M, paths []string) bson.M {
+	filtered := bson.M{}
+
+	for _, path := range removeEmptyStrings(paths) {
+		parts := strings.Split(path, ".")
+		copyPath(filtered, m, parts)
+
+		for section, v := range m {
+			sectionDoc, ok := v.(bson.M)
+			if !ok {
+				continue
+			}
+
+			dst, ok := filtered[section].(bson.M)
+			if !ok {
+				dst = bson.M{}
+			}
+			if copyPath(dst, sectionDoc, parts) {
+				filtered[section] = dst
+			}
+		}
+	}
+
+	return filtered
+}
+
+// copyPath copies the value of src at the path to dst, creating the intermediate documents.
+// It returns false if src has nothing at the path.
+func copyPath(dst, src bson.M, path []string) bool {
+	v, ok := src[path[0]]
+	if !ok {
+		return false
+	}
+
+	if len(path) == 1 {
+		dst[path[0]] = v
+
+		return true
+	}
+
+	srcDoc, ok := v.(bson.M)
+	if !ok {
+		return false
+	}
+
+	dstDoc, ok := dst[path[0]].(bson.M)
+	if !ok {
+		dstDoc = bson.M{}
+	}
+
+	if !copyPath(dstDoc, srcDoc, path[1:]) {
+		return false
+	}
+	dst[path[0]] = dstDoc
+
+	return true
+}
+
 // check interface.
 var _ prometheus.Collector = (*diagnosticDataCollector)(nil)
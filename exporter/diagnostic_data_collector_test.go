@@ -357,3 +357,52 @@ func TestDisconnectedDiagnosticDataCollector(t *testing.T) {
 	err = testutil.CollectAndCompare(c, expected, filter...)
 	assert.NoError(t, err)
 }
+
+func TestFilterDiagnosticData(t *testing.T) {
+	data := bson.M{
+		"start": int64(1),
+		"serverStatus": bson.M{
+			"uptime": int64(3600),
+			"wiredTiger": bson.M{
+				"cache": bson.M{
+					"bytes currently in the cache": int64(1024),
+					"maximum bytes configured":     int64(4096),
+				},
+				"transaction": bson.M{
+					"transaction begins": int64(50),
+				},
+			},
+			"metrics": bson.M{
+				"commands": bson.M{
+					"find": bson.M{"total": int64(12), "failed": int64(1)},
+				},
+				"document": bson.M{"inserted": int64(7)},
+			},
+		},
+		"replSetGetStatus": bson.M{
+			"myState": int32(1),
+		},
+	}
+
+	metricNames := func(m bson.M) []string {
+		var names []string
+		for _, metric := range makeMetrics("", m, nil, false) {
+			names = append(names, helpers.ReadMetric(metric).Name)
+		}
+		sort.Strings(names)
+
+		return names
+	}
+
+	filtered := filterDiagnosticData(data, []string{"wiredTiger.cache", "metrics.commands", "replSetGetStatus", "missing.path"})
+	assert.Equal(t, []string{
+		"mongodb_rs_myState",
+		"mongodb_ss_metrics_commands_find_failed",
+		"mongodb_ss_metrics_commands_find_total",
+		"mongodb_ss_wt_cache_bytes_currently_in_the_cache",
+		"mongodb_ss_wt_cache_maximum_bytes_configured",
+	}, metricNames(filtered))
+
+	// The document is not changed.
+	assert.Len(t, metricNames(data), 9)
+}
@@ -0,0 +1,131 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"crypto/md5" //nolint:gosec
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// digestTransport authenticates the requests with HTTP digest authentication, as required by the
+// Atlas Admin API. Every request is sent once without credentials to get the challenge.
+type digestTransport struct {
+	username string
+	password string
+	next     http.RoundTripper
+}
+
+func (t *digestTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	// The requests have no body so they can be sent twice.
+	res, err := next.RoundTrip(req.Clone(req.Context()))
+	if err != nil || res.StatusCode != http.StatusUnauthorized {
+		return res, err
+	}
+
+	challenge := parseDigestChallenge(res.Header.Get("WWW-Authenticate"))
+	_, _ = io.Copy(io.Discard, res.Body)
+	res.Body.Close() //nolint:errcheck
+
+	if challenge["nonce"] == "" {
+		return nil, errors.New("the server didn't send a digest authentication challenge")
+	}
+
+	cnonce, err := digestNonce()
+	if err != nil {
+		return nil, err
+	}
+
+	authReq := req.Clone(req.Context())
+	authReq.Header.Set("Authorization", digestAuthorization(challenge, req.Method, req.URL.RequestURI(), t.username, t.password, cnonce))
+
+	return next.RoundTrip(authReq)
+}
+
+// parseDigestChallenge returns the parameters of a WWW-Authenticate: Digest header.
+func parseDigestChallenge(header string) map[string]string {
+	params := make(map[string]string)
+
+	scheme, rest, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Digest") {
+		return params
+	}
+
+	for _, param := range strings.Split(rest, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok {
+			continue
+		}
+		params[strings.ToLower(k)] = strings.Trim(v, `"`)
+	}
+
+	return params
+}
+
+// digestAuthorization builds the Authorization header answering the challenge, as defined by RFC 2617.
+// Only the MD5 algorithm and the auth quality of protection are supported, like the Atlas API uses.
+func digestAuthorization(challenge map[string]string, method, uri, username, password, cnonce string) string {
+	const nc = "00000001"
+
+	ha1 := md5Hex(username + ":" + challenge["realm"] + ":" + password)
+	ha2 := md5Hex(method + ":" + uri)
+
+	var response string
+	qop := ""
+	if strings.Contains(challenge["qop"], "auth") {
+		qop = "auth"
+		response = md5Hex(strings.Join([]string{ha1, challenge["nonce"], nc, cnonce, qop, ha2}, ":"))
+	} else {
+		response = md5Hex(ha1 + ":" + challenge["nonce"] + ":" + ha2)
+	}
+
+	header := fmt.Sprintf(`Digest username="%s", realm="%s", nonce="%s", uri="%s", response="%s", algorithm=MD5`,
+		username, challenge["realm"], challenge["nonce"], uri, response)
+	if qop != "" {
+		header += fmt.Sprintf(`, qop=%s, nc=%s, cnonce="%s"`, qop, nc, cnonce)
+	}
+	if opaque := challenge["opaque"]; opaque != "" {
+		header += fmt.Sprintf(`, opaque="%s"`, opaque)
+	}
+
+	return header
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s)) //nolint:gosec
+
+	return hex.EncodeToString(sum[:])
+}
+
+func digestNonce() (string, error) {
+	b := make([]byte, 8) //nolint:gomnd
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "cannot make the digest authentication nonce")
+	}
+
+	return hex.EncodeToString(b), nil
+}
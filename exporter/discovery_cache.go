@@ -0,0 +1,242 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/sync/singleflight"
+)
+
+// namespaceNotFound is the error code returned when a collection doesn't exist anymore.
+const namespaceNotFound = 26
+
+type discoveryCacheKey struct{}
+
+// discoveryCache keeps the databases and collections found by the discovery between scrapes.
+// Listing them on every scrape is expensive on clusters having many collections and the list
+// rarely changes.
+// The keys have the topology generation, which changes when the exporter connects to another
+// topology or a collection is dropped, so the results of the previous generations are not used.
+// The same results are computed once for the concurrent scrapes, without blocking the scrapes
+// waiting for other results.
+type discoveryCache struct {
+	ttl time.Duration
+
+	lock       sync.Mutex
+	entries    map[string]discoveryEntry
+	generation uint64
+	topology   string
+	inFlight   singleflight.Group
+
+	// Make it testable.
+	now             func() time.Time
+	listDatabases   func(ctx context.Context, client *mongo.Client, filterInNamespaces []string, exclude []string) ([]string, error)
+	listCollections func(ctx context.Context, client *mongo.Client, filterInNamespaces []string, excludeDBs []string, skipViews bool) (map[string][]string, error)
+	listTimeseries  func(ctx context.Context, client *mongo.Client, filterInNamespaces []string, excludeDBs []string) ([]string, error)
+}
+
+type discoveryEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// newDiscoveryCache returns a cache keeping the discovery results for ttl.
+// If ttl is zero or negative, nil is returned and the discovery runs on every scrape.
+func newDiscoveryCache(ttl time.Duration) *discoveryCache {
+	if ttl <= 0 {
+		return nil
+	}
+
+	return &discoveryCache{
+		ttl:             ttl,
+		entries:         make(map[string]discoveryEntry),
+		now:             time.Now,
+		listDatabases:   databases,
+		listCollections: listAllCollections,
+		listTimeseries:  listTimeseriesCollections,
+	}
+}
+
+// withDiscoveryCache returns a context carrying the discovery cache so the collectors use it.
+func withDiscoveryCache(ctx context.Context, cache *discoveryCache) context.Context {
+	if cache == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, discoveryCacheKey{}, cache)
+}
+
+func discoveryCacheFromContext(ctx context.Context) *discoveryCache {
+	cache, _ := ctx.Value(discoveryCacheKey{}).(*discoveryCache)
+
+	return cache
+}
+
+// cachedDatabases is like databases but it uses the discovery cache in the context, if any.
+// The returned slice is a copy, it can be modified.
+func cachedDatabases(ctx context.Context, client *mongo.Client, filterInNamespaces []string, exclude []string) ([]string, error) {
+	cache := discoveryCacheFromContext(ctx)
+	if cache == nil {
+		return databases(ctx, client, filterInNamespaces, exclude)
+	}
+
+	key := fmt.Sprintf("databases %q %q", filterInNamespaces, exclude)
+	v, err := cache.get(key, func() (interface{}, error) {
+		return cache.listDatabases(ctx, client, filterInNamespaces, exclude)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]string(nil), v.([]string)...), nil //nolint:forcetypeassert
+}
+
+// cachedListAllCollections is like listAllCollections but it uses the discovery cache in the
+// context, if any. The returned map is a copy, it can be modified.
+func cachedListAllCollections(ctx context.Context, client *mongo.Client, filterInNamespaces []string, excludeDBs []string, skipViews bool) (map[string][]string, error) {
+	cache := discoveryCacheFromContext(ctx)
+	if cache == nil {
+		return listAllCollections(ctx, client, filterInNamespaces, excludeDBs, skipViews)
+	}
+
+	key := fmt.Sprintf("collections %q %q %t", filterInNamespaces, excludeDBs, skipViews)
+	v, err := cache.get(key, func() (interface{}, error) {
+		return cache.listCollections(ctx, client, filterInNamespaces, excludeDBs, skipViews)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	namespaces := v.(map[string][]string) //nolint:forcetypeassert
+	res := make(map[string][]string, len(namespaces))
+	for db, colls := range namespaces {
+		res[db] = append([]string(nil), colls...)
+	}
+
+	return res, nil
+}
+
+// cachedListTimeseriesCollections is like listTimeseriesCollections but it uses the discovery cache
+// in the context, if any. The returned slice is a copy, it can be modified.
+func cachedListTimeseriesCollections(ctx context.Context, client *mongo.Client, filterInNamespaces []string, excludeDBs []string) ([]string, error) {
+	cache := discoveryCacheFromContext(ctx)
+	if cache == nil {
+		return listTimeseriesCollections(ctx, client, filterInNamespaces, excludeDBs)
+	}
+
+	key := fmt.Sprintf("timeseries %q %q", filterInNamespaces, excludeDBs)
+	v, err := cache.get(key, func() (interface{}, error) {
+		return cache.listTimeseries(ctx, client, filterInNamespaces, excludeDBs)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]string(nil), v.([]string)...), nil //nolint:forcetypeassert
+}
+
+// invalidateDiscoveryCache drops the discovery results so the next scrape lists the databases
+// and collections again. It is used when a collection was dropped.
+func invalidateDiscoveryCache(ctx context.Context) {
+	discoveryCacheFromContext(ctx).newGeneration()
+}
+
+// setTopology starts a new generation if the topology, like the cluster ID and the replica set
+// name, is not the one of the previous scrapes, for example after the URI was pointed to another
+// cluster. It does nothing on a nil cache.
+func (c *discoveryCache) setTopology(topology string) {
+	if c == nil {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.topology == topology {
+		return
+	}
+
+	c.topology = topology
+	c.newGenerationLocked()
+}
+
+// newGeneration drops the results of the previous generations, including the ones still being
+// computed. It does nothing on a nil cache.
+func (c *discoveryCache) newGeneration() {
+	if c == nil {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.newGenerationLocked()
+}
+
+func (c *discoveryCache) newGenerationLocked() {
+	c.generation++
+	c.entries = make(map[string]discoveryEntry)
+}
+
+// get returns the cached value for key or calls fn to get it. The lock is not held while fn runs
+// and the concurrent calls for the same key share a single call of fn. Errors are not cached so
+// the next scrape retries. The value must not be modified since it is shared by the scrapes.
+func (c *discoveryCache) get(key string, fn func() (interface{}, error)) (interface{}, error) {
+	c.lock.Lock()
+	generation := c.generation
+	key = fmt.Sprintf("%d %s", generation, key)
+
+	if e, ok := c.entries[key]; ok && c.now().Before(e.expires) {
+		c.lock.Unlock()
+
+		return e.value, nil
+	}
+	c.lock.Unlock()
+
+	v, err, _ := c.inFlight.Do(key, func() (interface{}, error) {
+		v, err := fn()
+		if err != nil {
+			return nil, err
+		}
+
+		c.lock.Lock()
+		// The results computed during an older generation may already be stale.
+		if c.generation == generation {
+			c.entries[key] = discoveryEntry{value: v, expires: c.now().Add(c.ttl)}
+		}
+		c.lock.Unlock()
+
+		return v, nil
+	})
+
+	return v, err //nolint:wrapcheck
+}
+
+// isNamespaceNotFound returns true if err means the collection doesn't exist.
+func isNamespaceNotFound(err error) bool {
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) {
+		return cmdErr.Code == namespaceNotFound
+	}
+
+	return false
+}
@@ -0,0 +1,175 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestDiscoveryCache(t *testing.T) {
+	assert.Nil(t, newDiscoveryCache(0))
+
+	cache := newDiscoveryCache(time.Minute)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+
+	var calls int
+	cache.listCollections = func(context.Context, *mongo.Client, []string, []string, bool) (map[string][]string, error) {
+		calls++
+
+		return map[string][]string{"testdb": {"col1", "col2"}}, nil
+	}
+
+	ctx := withDiscoveryCache(context.Background(), cache)
+
+	for i := 0; i < 5; i++ {
+		collections, err := cachedListAllCollections(ctx, nil, nil, systemDBs, true)
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]string{"testdb": {"col1", "col2"}}, collections)
+	}
+	assert.Equal(t, 1, calls, "listCollections must run only once within the TTL")
+
+	// Different arguments are cached separately.
+	_, err := cachedListAllCollections(ctx, nil, []string{"testdb"}, systemDBs, true)
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+
+	now = now.Add(time.Minute)
+	_, err = cachedListAllCollections(ctx, nil, nil, systemDBs, true)
+	require.NoError(t, err)
+	assert.Equal(t, 3, calls, "the cache must be refreshed after the TTL")
+
+	// A dropped collection invalidates the cache.
+	invalidateDiscoveryCache(ctx)
+	_, err = cachedListAllCollections(ctx, nil, nil, systemDBs, true)
+	require.NoError(t, err)
+	assert.Equal(t, 4, calls)
+
+	var tsCalls int
+	cache.listTimeseries = func(context.Context, *mongo.Client, []string, []string) ([]string, error) {
+		tsCalls++
+
+		return []string{"testdb.weather"}, nil
+	}
+
+	for i := 0; i < 5; i++ {
+		namespaces, err := cachedListTimeseriesCollections(ctx, nil, []string{"testdb.weather"}, systemDBs)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"testdb.weather"}, namespaces)
+	}
+	assert.Equal(t, 1, tsCalls, "listTimeseriesCollections must run only once within the TTL")
+	assert.Equal(t, 4, calls, "the time-series collections are cached separately")
+}
+
+func TestDiscoveryCacheConcurrency(t *testing.T) {
+	cache := newDiscoveryCache(time.Minute)
+
+	var calls int32
+	release := make(chan struct{})
+	cache.listCollections = func(context.Context, *mongo.Client, []string, []string, bool) (map[string][]string, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+
+		return map[string][]string{"testdb": {"col1", "col2"}}, nil
+	}
+	cache.listDatabases = func(context.Context, *mongo.Client, []string, []string) ([]string, error) {
+		return []string{"testdb"}, nil
+	}
+
+	ctx := withDiscoveryCache(context.Background(), cache)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			collections, err := cachedListAllCollections(ctx, nil, nil, systemDBs, true)
+			assert.NoError(t, err)
+			assert.Equal(t, map[string][]string{"testdb": {"col1", "col2"}}, collections)
+		}()
+	}
+
+	// The other keys are not blocked by the slow listing.
+	dbs, err := cachedDatabases(ctx, nil, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"testdb"}, dbs)
+
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&calls) == 1 }, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls), "the concurrent scrapes must share the listing")
+
+	// The callers get copies, modifying them doesn't change the cached results.
+	dbs[0] = "modified"
+	collections, err := cachedListAllCollections(ctx, nil, nil, systemDBs, true)
+	require.NoError(t, err)
+	collections["testdb"][0] = "modified"
+
+	dbs, err = cachedDatabases(ctx, nil, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"testdb"}, dbs)
+	collections, err = cachedListAllCollections(ctx, nil, nil, systemDBs, true)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"testdb": {"col1", "col2"}}, collections)
+}
+
+func TestDiscoveryCacheTopologyGeneration(t *testing.T) {
+	cache := newDiscoveryCache(time.Minute)
+
+	var calls int
+	cache.listDatabases = func(context.Context, *mongo.Client, []string, []string) ([]string, error) {
+		calls++
+
+		return []string{"testdb"}, nil
+	}
+
+	ctx := withDiscoveryCache(context.Background(), cache)
+	list := func() {
+		_, err := cachedDatabases(ctx, nil, nil, nil)
+		require.NoError(t, err)
+	}
+
+	cache.setTopology("cluster1/rs1")
+	list()
+	cache.setTopology("cluster1/rs1")
+	list()
+	assert.Equal(t, 1, calls, "the same topology keeps the cached results")
+
+	cache.setTopology("cluster2/rs1")
+	list()
+	assert.Equal(t, 2, calls, "another topology must not use the results of the previous one")
+
+	// A nil cache, when the cache is disabled, is a no-op.
+	var disabled *discoveryCache
+	disabled.setTopology("cluster1/rs1")
+	disabled.newGeneration()
+}
+
+func TestIsNamespaceNotFound(t *testing.T) {
+	assert.True(t, isNamespaceNotFound(mongo.CommandError{Code: 26, Name: "NamespaceNotFound"}))
+	assert.False(t, isNamespaceNotFound(mongo.CommandError{Code: 13, Name: "Unauthorized"}))
+	assert.False(t, isNamespaceNotFound(context.DeadlineExceeded))
+}
@@ -0,0 +1,119 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// electionReasons are the sections of serverStatus.electionMetrics counting the elections called
+// by the member for each reason.
+var electionReasons = []string{
+	"stepUpCmd",
+	"priorityTakeover",
+	"catchUpTakeover",
+	"electionTimeout",
+	"freezeTimeout",
+}
+
+type electionCollector struct {
+	ctx  context.Context
+	base *baseCollector
+}
+
+// newElectionCollector creates a collector for the election metrics reported by serverStatus.electionMetrics.
+func newElectionCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger) *electionCollector {
+	return &electionCollector{
+		ctx:  ctx,
+		base: newBaseCollector(client, logger),
+	}
+}
+
+func (d *electionCollector) Describe(ch chan<- *prometheus.Desc) {
+	d.base.Describe(d.ctx, ch, d.collect)
+}
+
+func (d *electionCollector) Collect(ch chan<- prometheus.Metric) {
+	d.base.Collect(ch)
+}
+
+func (d *electionCollector) collect(ch chan<- prometheus.Metric) {
+	defer measureCollectTime(ch, "mongodb", "election")()
+
+	logger := d.base.logger
+
+	m, err := serverStatus(d.ctx, d.base.client)
+	if err != nil {
+		logger.Errorf("cannot get election stats: %s", err)
+
+		return
+	}
+
+	for _, metric := range electionMetrics(m) {
+		ch <- metric
+	}
+}
+
+// electionMetrics returns the election counters from a serverStatus document.
+// Only replica set members report electionMetrics so no metrics are returned for standalone instances.
+func electionMetrics(m bson.M) []prometheus.Metric {
+	em, ok := m["electionMetrics"].(bson.M)
+	if !ok {
+		return nil
+	}
+
+	calledDesc := prometheus.NewDesc("mongodb_mongod_replset_elections_called_total",
+		"Number of elections called by the member, by reason.", []string{"reason"}, nil)
+	successfulDesc := prometheus.NewDesc("mongodb_mongod_replset_elections_successful_total",
+		"Number of elections called by the member that it won, by reason.", []string{"reason"}, nil)
+
+	var metrics []prometheus.Metric
+	var candidate float64
+	var hasCandidate bool
+
+	for _, reason := range electionReasons {
+		section, ok := em[reason].(bson.M)
+		if !ok {
+			continue
+		}
+
+		if f, err := asFloat64(section["called"]); err == nil && f != nil {
+			metrics = append(metrics, prometheus.MustNewConstMetric(calledDesc, prometheus.CounterValue, *f, reason))
+			candidate += *f
+			hasCandidate = true
+		}
+
+		if f, err := asFloat64(section["successful"]); err == nil && f != nil {
+			metrics = append(metrics, prometheus.MustNewConstMetric(successfulDesc, prometheus.CounterValue, *f, reason))
+		}
+	}
+
+	// Every election called by the member makes it stand as a candidate.
+	if hasCandidate {
+		d := prometheus.NewDesc("mongodb_mongod_replset_election_candidate_total",
+			"Number of elections the member stood as a candidate in.", nil, nil)
+		metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.CounterValue, candidate))
+	}
+
+	return metrics
+}
+
+var _ prometheus.Collector = (*electionCollector)(nil)
@@ -0,0 +1,64 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestElectionMetrics(t *testing.T) {
+	m := bson.M{
+		"electionMetrics": bson.M{
+			"stepUpCmd":                      bson.M{"called": int64(1), "successful": int64(1)},
+			"priorityTakeover":               bson.M{"called": int64(2), "successful": int64(1)},
+			"catchUpTakeover":                bson.M{"called": int64(0), "successful": int64(0)},
+			"electionTimeout":                bson.M{"called": int64(3), "successful": int64(2)},
+			"freezeTimeout":                  bson.M{"called": int64(0), "successful": int64(0)},
+			"numStepDownsCausedByHigherTerm": int64(1),
+			"numCatchUps":                    int64(0),
+		},
+	}
+
+	expected := strings.NewReader(`
+	# HELP mongodb_mongod_replset_election_candidate_total Number of elections the member stood as a candidate in.
+	# TYPE mongodb_mongod_replset_election_candidate_total counter
+	mongodb_mongod_replset_election_candidate_total 6
+	# HELP mongodb_mongod_replset_elections_called_total Number of elections called by the member, by reason.
+	# TYPE mongodb_mongod_replset_elections_called_total counter
+	mongodb_mongod_replset_elections_called_total{reason="catchUpTakeover"} 0
+	mongodb_mongod_replset_elections_called_total{reason="electionTimeout"} 3
+	mongodb_mongod_replset_elections_called_total{reason="freezeTimeout"} 0
+	mongodb_mongod_replset_elections_called_total{reason="priorityTakeover"} 2
+	mongodb_mongod_replset_elections_called_total{reason="stepUpCmd"} 1
+	# HELP mongodb_mongod_replset_elections_successful_total Number of elections called by the member that it won, by reason.
+	# TYPE mongodb_mongod_replset_elections_successful_total counter
+	mongodb_mongod_replset_elections_successful_total{reason="catchUpTakeover"} 0
+	mongodb_mongod_replset_elections_successful_total{reason="electionTimeout"} 2
+	mongodb_mongod_replset_elections_successful_total{reason="freezeTimeout"} 0
+	mongodb_mongod_replset_elections_successful_total{reason="priorityTakeover"} 1
+	mongodb_mongod_replset_elections_successful_total{reason="stepUpCmd"} 1` + "\n")
+
+	err := testutil.CollectAndCompare(metricsCollector(electionMetrics(m)), expected)
+	assert.NoError(t, err)
+
+	// Standalone instances don't have electionMetrics.
+	assert.Empty(t, electionMetrics(bson.M{"ok": float64(1)}))
+}
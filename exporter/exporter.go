@@ -19,16 +19,29 @@ package exporter
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
-	_ "net/http/pprof"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/version"
 	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/tag"
+	"google.golang.org/grpc"
 
 	"github.com/percona/mongodb_exporter/exporter/dsn_fix"
 )
@@ -36,11 +49,22 @@ import (
 // Exporter holds Exporter methods and attributes.
 type Exporter struct {
 	client                *mongo.Client
+	clientHealthy         bool
+	configClient          *mongo.Client
 	clientMu              sync.Mutex
 	logger                *logrus.Logger
 	opts                  *Opts
 	lock                  *sync.Mutex
 	totalCollectionsCount int
+	cancel                context.CancelFunc
+	counters              *counterResets
+	discovery             *discoveryCache
+	scrapes               *scrapeMetrics
+	grpcServer            *grpc.Server
+	grpcListener          net.Listener
+	helpTexts             *helpTexts
+	customQueries         []*customQuery
+	tlsWatcher            *fsnotify.Watcher
 }
 
 // Opts holds new exporter options.
@@ -52,6 +76,8 @@ type Opts struct {
 	CompatibleMode         bool
 	DirectConnect          bool
 	ConnectTimeoutMS       int
+	SRVMaxHosts            int
+	HeartbeatInterval      time.Duration
 	DisableDefaultRegistry bool
 	DiscoveringMode        bool
 	GlobalConnPool         bool
@@ -59,22 +85,202 @@ type Opts struct {
 	TimeoutOffset          int
 	CurrentOpSlowTime      string
 
-	CollectAll               bool
-	EnableDBStats            bool
-	EnableDBStatsFreeStorage bool
-	EnableDiagnosticData     bool
-	EnableReplicasetStatus   bool
-	EnableCurrentopMetrics   bool
-	EnableTopMetrics         bool
-	EnableIndexStats         bool
-	EnableCollStats          bool
-	EnableProfile            bool
-	EnableShards             bool
+	// CompatibleModeCollectors enables or disables the compatible mode for the given collectors, by
+	// name. It takes precedence over CompatibleMode, which is used for the collectors not in the map.
+	CompatibleModeCollectors map[string]bool
+
+	// InstanceTotalsExcludeDBs are the databases left out of the instance totals of the dbstats
+	// collector. If it is nil, the system databases are excluded.
+	InstanceTotalsExcludeDBs []string
+
+	// WatchTLSFiles rebuilds the global connection pool client and the config servers client when
+	// the TLS files of the URI or of ConfigServerURI, like tlsCertificateKeyFile or tlsCAFile,
+	// change so short-lived certificates are picked up without a restart. Without the global
+	// connection pool, the main client already reads the files on every scrape but the config
+	// servers client is kept between scrapes, so the files are watched if ConfigServerURI is set.
+	WatchTLSFiles bool
+
+	// ServerSelectionTimeoutMS bounds how long an operation waits for a suitable server, for example
+	// when the primary is down. If it is not set, the serverSelectionTimeoutMS option of the URI is
+	// used, then the default scrape timeout.
+	ServerSelectionTimeoutMS int
+
+	// SocketTimeoutMS and MaxConnIdleTimeMS set the driver's socket timeout and the time a pooled
+	// connection can stay idle before it is closed. On networks dropping idle connections, a max idle
+	// time shorter than the firewall timeout reaps the stale sockets before a scrape uses them.
+	// 0 keeps the driver defaults.
+	SocketTimeoutMS   int
+	MaxConnIdleTimeMS int
+
+	// BypassAutoEncryption and KeyVaultNamespace let the exporter connect to clusters requiring
+	// clients to declare client-side field level encryption settings. Auto encryption is bypassed
+	// so no KMS credentials are needed. Both must be set together.
+	BypassAutoEncryption bool
+	KeyVaultNamespace    string
+
+	// AppName is the application name sent to MongoDB and shown in the server logs. If it is not
+	// set, the appName of the URI is used or mongodb_exporter if the URI doesn't have one.
+	AppName string
+
+	// DriverOptions are connection string options, like retryWrites or w, added to the URI query.
+	// The options managed by other fields or disabling the TLS verification cannot be set.
+	DriverOptions map[string]string
+
+	// ReadPreference is the read preference mode, like secondaryPreferred. If it is empty, the
+	// mode of the URI is used.
+	ReadPreference string
+	// ReadPreferenceTagSets selects the members to read from by their tags, for example
+	// {"nodeType": "ANALYTICS"} for the analytics nodes. The read preference cannot be primary.
+	ReadPreferenceTagSets []map[string]string
+	// ReadConcern is the read concern level (local, majority, linearizable or snapshot) of the
+	// exporter queries. majority makes sure the config database read through mongos, like the
+	// chunks, isn't rolled back. If it is empty, the readConcernLevel of the URI is used.
+	ReadConcern string
+
+	// ServerAPIVersion declares the Stable API version, 1 is the only one, used by the exporter.
+	ServerAPIVersion string
+	// ServerAPIStrict makes the server reject the commands outside the Stable API. Only the
+	// collectors in stableAPICollectors run then since the others rely on commands like
+	// serverStatus or $collStats which are not part of it.
+	ServerAPIStrict bool
+
+	// ShardCollStatsNamespaces restricts the shards collector to these sharded collections.
+	// If it is empty, all the collections are collected through mongos or the config servers
+	// and none when connected to a shard member.
+	ShardCollStatsNamespaces []string
+
+	// ConfigServerURI is the URI of the config server replica set. If it is set, the config
+	// database is read directly from the config servers instead of through mongos.
+	ConfigServerURI string
+
+	// MongosStaleThreshold is how old the last ping of a mongos to the config servers can be for
+	// the mongos collector to report it as up. 0 means defaultMongosStaleThreshold.
+	MongosStaleThreshold time.Duration
+
+	// DiscoveryCacheTTL is how long the databases and collections found by the discovery are
+	// kept. 0 means they are listed on every scrape.
+	DiscoveryCacheTTL time.Duration
+
+	// PrewarmOnStart makes New run the discovery in background so the first scrape doesn't
+	// have to wait for it.
+	PrewarmOnStart bool
+
+	// CollectorPriority lists collector names in the order they must run. The collectors not
+	// listed run after them in the default order. Since the collectors run one after the other,
+	// it decides which metrics are still available if the scrape times out.
+	CollectorPriority []string
+
+	// MonotonicCounters exposes counters like opcounters, network bytes and asserts as counters
+	// which are not reset when the server restarts.
+	MonotonicCounters bool
+
+	// NormalizeUnits exposes the metrics in milliseconds, microseconds or megabytes listed in
+	// normalizedUnits in seconds and bytes, with a name ending in _seconds or _bytes.
+	NormalizeUnits bool
+
+	// HelpTextOverrideFile is a YAML file mapping metric names to the help text exposed instead of
+	// the built-in one, for example to match the documentation of an organization.
+	HelpTextOverrideFile string
+
+	// CustomQueryFile is a YAML file of named aggregation pipelines, each one exposed as a
+	// mongodb_custom_<name> gauge. See customQuery for the fields of a query.
+	CustomQueryFile string
+
+	// OmitHelpText removes the HELP and TYPE comments from the response to reduce its size.
+	OmitHelpText bool
+
+	// MaxLabelValueLength truncates the label values longer than this limit. 0 means no limit.
+	MaxLabelValueLength int
+
+	// AtlasAPIPublicKey and AtlasAPIPrivateKey are the Atlas Admin API key used to read the process
+	// measurements of the Atlas project AtlasGroupID, for the metrics serverless instances don't have
+	// in serverStatus. The atlas collector runs when the three of them are set.
+	AtlasAPIPublicKey  string
+	AtlasAPIPrivateKey string
+	AtlasGroupID       string
+
+	// PushGatewayURL is the Pushgateway used by PushOnce if it isn't given one. PushGroupingLabels
+	// are added to the job to group the metrics, like instance.
+	PushGatewayURL     string
+	PushGroupingLabels map[string]string
+
+	// EnableGRPCHealth serves the gRPC health checking protocol on GRPCHealthAddr, reporting
+	// SERVING when MongoDB can be pinged. The server is stopped by Shutdown.
+	EnableGRPCHealth bool
+	GRPCHealthAddr   string
+
+	// FailScrapeOnDown makes the metrics handler answer 503 instead of 200 when mongodb_up is 0.
+	// The metrics gathered are still in the response body.
+	FailScrapeOnDown bool
+
+	// CollStatsTopN, in discovering mode, restricts the collstats metrics to the N collections using
+	// the most storage, found with a $collStats returning only the size. 0 means all the collections.
+	// CollStatsLimit still disables the collector if there are more collections than the limit.
+	CollStatsTopN int
+
+	// CollStatsConcurrency is the number of $collStats commands run in parallel by the collstats collector.
+	CollStatsConcurrency int
+
+	// CommandMetricsAllowlist restricts the command metrics to these commands. If it is empty,
+	// all the commands are exposed except <UNKNOWN>, which counts the unknown commands.
+	CommandMetricsAllowlist []string
+
+	// DiagnosticDataPaths restricts the diagnostic data metrics to the parts of getDiagnosticData
+	// under these dotted paths, like wiredTiger.cache or metrics.commands. Empty means all of it.
+	DiagnosticDataPaths []string
+
+	// EnableCollectionWiredTiger adds the WiredTiger cache and block manager stats of every collection
+	// to the collstats metrics. It is not enabled by CollectAll since it adds series per collection.
+	EnableCollectionWiredTiger bool
+
+	// EnableIndexSizes adds the size of every index to the collstats metrics. It is not enabled
+	// by CollectAll since collections can have many indexes.
+	EnableIndexSizes bool
+
+	CollectAll                bool
+	EnableDBStats             bool
+	EnableDBStatsFreeStorage  bool
+	EnableInstanceTotals      bool
+	EnableDiagnosticData      bool
+	EnableReplicasetStatus    bool
+	EnableCurrentopMetrics    bool
+	EnableTopMetrics          bool
+	EnableIndexStats          bool
+	EnableCollStats           bool
+	EnableProfile             bool
+	EnableShards              bool
+	EnableGlobalLock          bool
+	EnableAsserts             bool
+	EnableStorageStats        bool
+	EnableMemoryStats         bool
+	EnableCursorStats         bool
+	EnableFlowControl         bool
+	EnableElectionStats       bool
+	EnableQueryExecutorStats  bool
+	EnableLockStats           bool
+	EnableTransactionStats    bool
+	EnableSecurityStats       bool
+	EnableMongos              bool
+	EnableReplMetrics         bool
+	EnableCommandMetrics      bool
+	EnableBalancerStats       bool
+	EnableTTLStats            bool
+	EnableFreeMonitoringStats bool
+	EnableUserStats           bool
+	EnableShardReplLag        bool
+	FanOutToMembers           bool
 
 	EnableOverrideDescendingIndex bool
 
+	// UpHostLabel adds the target host and the scrape error labels to mongodb_up.
+	// Useful in multi-target mode, where several targets are exposed by the same exporter.
+	UpHostLabel bool
+
 	IndexStatsCollections []string
 	Logger                *logrus.Logger
+	// LogFormat (text or json) and LogLevel are used to build the logger if Logger is not set.
+	LogFormat string
+	LogLevel  string
 
 	URI string
 }
@@ -82,12 +288,74 @@ type Opts struct {
 var (
 	errCannotHandleType   = fmt.Errorf("don't know how to handle data type")
 	errUnexpectedDataType = fmt.Errorf("unexpected data type")
+
+	errDirectConnectWithSRV = fmt.Errorf("direct connect cannot be used with a mongodb+srv URI")
+
+	errUnknownCollector     = fmt.Errorf("unknown collector")
+	errReadPreferenceTags   = fmt.Errorf("read preference tags cannot be used with the primary read preference")
+	errReadConcern          = fmt.Errorf("invalid read concern")
+	errLinearizableRead     = fmt.Errorf("linearizable read concern requires the primary read preference")
+	errReservedDriverOption = fmt.Errorf("driver option cannot be set with the driver options")
+	errServerAPIVersion     = fmt.Errorf("unsupported server API version")
+	errServerAPIStrict      = fmt.Errorf("server API strict mode requires a server API version")
+	errUnsetURIVariable     = fmt.Errorf("environment variable used in the URI is not set")
+	errNoPushGateway        = fmt.Errorf("no Pushgateway URL to push the metrics to")
+	errShardNotReplicaSet   = fmt.Errorf("shard is not a replica set")
+
+	errAutoEncryptionOptions      = fmt.Errorf("bypass auto encryption and the key vault namespace must be set together")
+	errAutoEncryptionNotSupported = fmt.Errorf("auto encryption options require building the exporter with the cse tag")
 )
 
 const (
 	defaultCacheSize = 1000
+	// defaultScrapeTimeoutSeconds is used when Prometheus doesn't send the scrape timeout header.
+	defaultScrapeTimeoutSeconds = 10
+	// localMasterKeySize is the size of the master key of the local KMS provider.
+	localMasterKeySize = 96
+	// defaultAppName identifies the exporter connections in the server logs.
+	defaultAppName = "mongodb_exporter"
+	// defaultMongosStaleThreshold is the ping age after which sh.status() stops listing a mongos as active.
+	defaultMongosStaleThreshold = time.Minute
 )
 
+// Collector names. They are the values accepted by the collect[] filter.
+const (
+	collectorDiagnosticData     = "diagnosticdata"
+	collectorReplicasetStatus   = "replicasetstatus"
+	collectorDBStats            = "dbstats"
+	collectorTopMetrics         = "topmetrics"
+	collectorCurrentopMetrics   = "currentopmetrics"
+	collectorIndexStats         = "indexstats"
+	collectorCollStats          = "collstats"
+	collectorProfile            = "profile"
+	collectorShards             = "shards"
+	collectorGlobalLock         = "globallock"
+	collectorAsserts            = "asserts"
+	collectorStorageStats       = "storagestats"
+	collectorMemoryStats        = "memorystats"
+	collectorCursorStats        = "cursorstats"
+	collectorFlowControl        = "flowcontrol"
+	collectorElectionStats      = "electionstats"
+	collectorQueryExecutorStats = "queryexecutorstats"
+	collectorLockStats          = "lockstats"
+	collectorTransactionStats   = "transactionstats"
+	collectorSecurityStats      = "securitystats"
+	collectorMongos             = "mongos"
+	collectorReplMetrics        = "replmetrics"
+	collectorCommandMetrics     = "commandmetrics"
+	collectorBalancer           = "balancer"
+	collectorTTL                = "ttlstats"
+	collectorFreeMonitoring     = "freemonitoring"
+	collectorUsers              = "users"
+	collectorShardReplLag       = "shardrepllag"
+	collectorAtlas              = "atlas"
+	collectorCustomQueries      = "customqueries"
+	collectorMembers            = "members"
+)
+
+// collectorUp can be used in the collect[] filter to get only mongodb_up, which is always exposed.
+const collectorUp = "up"
+
 // New connects to the database and returns a new Exporter instance.
 func New(opts *Opts) *Exporter {
 	if opts == nil {
@@ -95,19 +363,67 @@ func New(opts *Opts) *Exporter {
 	}
 
 	if opts.Logger == nil {
-		opts.Logger = logrus.New()
+		opts.Logger = newLogger(opts.LogFormat, opts.LogLevel)
 	}
 
-	ctx := context.Background()
+	ctx, cancel := context.WithCancel(context.Background())
 
 	exp := &Exporter{
 		logger:                opts.Logger,
 		opts:                  opts,
 		lock:                  &sync.Mutex{},
 		totalCollectionsCount: -1, // Not calculated yet. waiting the db connection.
+		cancel:                cancel,
+		counters:              newCounterResets(),
+		discovery:             newDiscoveryCache(opts.DiscoveryCacheTTL),
+		scrapes:               newScrapeMetrics(),
+	}
+
+	if exp.logger.IsLevelEnabled(logrus.DebugLevel) {
+		exp.logger.Debugf("Configuration:\n%s", DumpConfig(opts))
+	}
+
+	if opts.FanOutToMembers && strings.HasPrefix(opts.URI, "mongodb+srv://") {
+		exp.logger.Warn("The members collector is disabled: it needs direct connections to the members, " +
+			"which cannot be made with a mongodb+srv URI")
+	}
+
+	if opts.HelpTextOverrideFile != "" {
+		texts, err := loadHelpTexts(opts.HelpTextOverrideFile, exp.logger)
+		if err != nil {
+			exp.logger.Errorf("Cannot load the help texts, using the built-in ones: %v", err)
+		}
+		exp.helpTexts = texts
 	}
+
+	if opts.CustomQueryFile != "" {
+		queries, err := loadCustomQueries(opts.CustomQueryFile)
+		if err != nil {
+			exp.logger.Errorf("Cannot load the custom queries: %v", err)
+		}
+		exp.customQueries = queries
+	}
+
+	if opts.WatchTLSFiles && (opts.GlobalConnPool || opts.ConfigServerURI != "") {
+		if err := exp.startTLSWatcher(); err != nil {
+			exp.logger.Errorf("Cannot watch the TLS files: %v", err)
+		}
+	}
+
+	if opts.EnableGRPCHealth && opts.GRPCHealthAddr != "" {
+		if err := exp.startGRPCHealth(ctx.Done()); err != nil {
+			exp.logger.Errorf("Cannot start the gRPC health server: %v", err)
+		}
+	}
+
 	// Try initial connect. Connection will be retried with every scrape.
 	go func() {
+		if opts.PrewarmOnStart {
+			exp.prewarm(ctx)
+
+			return
+		}
+
 		_, err := exp.getClient(ctx)
 		if err != nil {
 			exp.logger.Errorf("Cannot connect to MongoDB: %v", err)
@@ -117,6 +433,203 @@ func New(opts *Opts) *Exporter {
 	return exp
 }
 
+// prewarm connects to the database and runs the discovery used to decide which collectors are
+// enabled, and the one of the enabled collectors, so it is already cached when the first scrape
+// arrives.
+func (e *Exporter) prewarm(ctx context.Context) {
+	client, err := e.getClient(ctx)
+	if err != nil {
+		e.logger.Errorf("Cannot connect to MongoDB: %v", err)
+
+		return
+	}
+
+	if !e.opts.GlobalConnPool {
+		defer func() {
+			if err := client.Disconnect(ctx); err != nil {
+				e.logger.Errorf("Cannot disconnect client: %v", err)
+			}
+		}()
+	}
+
+	// The topology detection opens the connections used by the first scrape.
+	ti := newTopologyInfo(ctx, client, e.logger)
+	e.logger.Debugf("Prewarm topology labels: %v", ti.baseLabels())
+
+	e.prewarmDiscovery(ctx, client)
+
+	count, err := nonSystemCollectionsCount(ctx, client, nil, nil)
+	if err != nil {
+		e.logger.Warnf("Cannot prewarm the collections count: %v", err)
+
+		return
+	}
+
+	e.lock.Lock()
+	if e.totalCollectionsCount < 0 {
+		e.totalCollectionsCount = count
+	}
+	e.lock.Unlock()
+
+	e.logger.Debugf("Prewarm done, %d collections found", count)
+}
+
+// prewarmDiscovery fills the discovery cache, if any, with the databases and collections listed by
+// the enabled collectors, using the same filters so the scrapes find them.
+func (e *Exporter) prewarmDiscovery(ctx context.Context, client *mongo.Client) {
+	if e.discovery == nil {
+		return
+	}
+
+	ctx = withDiscoveryCache(ctx, e.discovery)
+
+	if e.opts.EnableDBStats {
+		if _, err := cachedDatabases(ctx, client, nil, nil); err != nil {
+			e.logger.Warnf("Cannot prewarm the databases: %v", err)
+		}
+	}
+
+	if !e.opts.DiscoveringMode {
+		return
+	}
+
+	if e.opts.EnableCollStats {
+		if _, err := cachedListAllCollections(ctx, client, e.opts.CollStatsNamespaces, systemDBs, true); err != nil {
+			e.logger.Warnf("Cannot prewarm the collections: %v", err)
+		}
+
+		if _, err := cachedListTimeseriesCollections(ctx, client, e.opts.CollStatsNamespaces, systemDBs); err != nil {
+			e.logger.Warnf("Cannot prewarm the time-series collections: %v", err)
+		}
+	}
+
+	if e.opts.EnableIndexStats {
+		if _, err := cachedListAllCollections(ctx, client, e.opts.IndexStatsCollections, systemDBs, true); err != nil {
+			e.logger.Warnf("Cannot prewarm the collections: %v", err)
+		}
+	}
+}
+
+// Shutdown stops the background work started by New and disconnects the global connection pool
+// client, if any.
+func (e *Exporter) Shutdown(ctx context.Context) error {
+	if e.cancel != nil {
+		e.cancel()
+	}
+
+	if e.grpcServer != nil {
+		e.grpcServer.GracefulStop()
+	}
+
+	if e.tlsWatcher != nil {
+		if err := e.tlsWatcher.Close(); err != nil {
+			e.logger.Warnf("Cannot stop watching the TLS files: %v", err)
+		}
+		e.tlsWatcher = nil
+	}
+
+	e.clientMu.Lock()
+	defer e.clientMu.Unlock()
+
+	var err error
+	if e.configClient != nil {
+		err = e.configClient.Disconnect(ctx)
+		e.configClient = nil
+	}
+
+	if e.client != nil {
+		if cerr := e.client.Disconnect(ctx); cerr != nil {
+			err = cerr
+		}
+		e.client = nil
+	}
+
+	return err
+}
+
+// ping checks MongoDB can be reached with the client used for the scrapes.
+func (e *Exporter) ping(ctx context.Context) error {
+	client, err := e.getClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer e.releaseClient(ctx, client)
+
+	if err := pingMongoDB(ctx, client); err != nil {
+		if e.opts.GlobalConnPool {
+			e.markClientUnhealthy(client)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// atlasEnabled returns true if the Atlas API key and project are set.
+func (o *Opts) atlasEnabled() bool {
+	return o.AtlasAPIPublicKey != "" && o.AtlasAPIPrivateKey != "" && o.AtlasGroupID != ""
+}
+
+// compatibleMode returns true if the collector exposes the old mongodb_exporter 0.1x metric names.
+func (o *Opts) compatibleMode(collector string) bool {
+	if enabled, ok := o.CompatibleModeCollectors[collector]; ok {
+		return enabled
+	}
+
+	return o.CompatibleMode
+}
+
+// scrapeTimeout returns the time left to answer a scrape: the timeout sent by Prometheus with the
+// request r, or the default one if r is nil or has no timeout, minus TimeoutOffset. The offset is
+// ignored if it is not shorter than the timeout.
+func (o *Opts) scrapeTimeout(r *http.Request) time.Duration {
+	seconds := defaultScrapeTimeoutSeconds
+	if r != nil {
+		// To support also older ones vmagents.
+		if s, err := strconv.Atoi(r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds")); err == nil {
+			seconds = s
+		}
+	}
+
+	if seconds > o.TimeoutOffset {
+		seconds -= o.TimeoutOffset
+	}
+
+	return time.Duration(seconds) * time.Second
+}
+
+// newLogger creates a logger with the given format and level.
+// Invalid values fall back to the text format and the info level.
+func newLogger(format, level string) *logrus.Logger {
+	logger := logrus.New()
+
+	var warnings []string
+
+	switch format {
+	case "", "text":
+	case "json":
+		logger.SetFormatter(&logrus.JSONFormatter{})
+	default:
+		warnings = append(warnings, fmt.Sprintf("invalid log format %q, falling back to \"text\"", format))
+	}
+
+	if level != "" {
+		lvl, err := logrus.ParseLevel(level)
+		if err != nil {
+			lvl = logrus.InfoLevel
+			warnings = append(warnings, fmt.Sprintf("invalid log level %q, falling back to \"info\"", level))
+		}
+		logger.SetLevel(lvl)
+	}
+
+	for _, w := range warnings {
+		logger.Warn(w)
+	}
+
+	return logger
+}
+
 func (e *Exporter) getTotalCollectionsCount() int {
 	e.lock.Lock()
 	defer e.lock.Unlock()
@@ -127,18 +640,82 @@ func (e *Exporter) getTotalCollectionsCount() int {
 func (e *Exporter) makeRegistry(ctx context.Context, client *mongo.Client, topologyInfo labelsGetter, requestOpts Opts) *prometheus.Registry {
 	registry := prometheus.NewRegistry()
 
-	gc := newGeneralCollector(ctx, client, e.opts.Logger)
+	// Collectors reading from serverStatus share a single document per scrape.
+	ctx = withServerStatusCache(ctx)
+	ctx = withDiscoveryCache(ctx, e.discovery)
+	ctx = withPermissionErrors(ctx)
+
+	var upHost string
+	if e.opts.UpHostLabel {
+		upHost = hostFromURI(e.opts.URI)
+	}
+
+	var nodeType mongoDBNodeType
+	var states []collectorState
+	if client != nil {
+		var err error
+		nodeType, err = getNodeType(ctx, client)
+		if err != nil {
+			e.logger.Errorf("Registry - Cannot get node type to check if this is a mongos : %s", err)
+
+			// Fall back to the role found by the topology detection, otherwise the collectors not
+			// supported by mongos, like replSetGetStatus, would be built and log errors on every scrape.
+			if topologyInfo != nil && topologyInfo.baseLabels()[labelClusterRole] == string(typeMongos) {
+				nodeType = typeMongos
+			}
+		}
+
+		if topologyInfo != nil {
+			labels := topologyInfo.baseLabels()
+			e.discovery.setTopology(labels[labelClusterID] + "/" + labels[labelReplicasetName])
+		}
+
+		states = e.collectorStates(nodeType, requestOpts)
+		if e.opts.ServerAPIStrict {
+			states = e.stableAPIStates(states)
+		}
+	}
+
+	// In compatible mode the diagnostic data collector already exposes the replica set state.
+	replsetState := !e.opts.compatibleMode(collectorDiagnosticData) || !collectorEnabled(states, collectorDiagnosticData)
+
+	gc := newGeneralCollector(ctx, client, e.opts.Logger, upHost, replsetState)
+	// mongos doesn't have a feature compatibility version of its own.
+	gc.featureCompatibility = nodeType != typeMongos
+	if e.opts.GlobalConnPool {
+		gc.onPingError = func() { e.markClientUnhealthy(client) }
+	}
 	registry.MustRegister(gc)
 
 	if client == nil {
 		return registry
 	}
 
-	nodeType, err := getNodeType(ctx, client)
-	if err != nil {
-		e.logger.Errorf("Registry - Cannot get node type to check if this is a mongos : %s", err)
+	// Collectors run when they are registered so this is also the order they are collected.
+	for _, state := range prioritizeCollectors(states, e.opts.CollectorPriority) {
+		if !state.enabled {
+			continue
+		}
+
+		registry.MustRegister(e.newCollector(withCollectorName(ctx, state.name), client, topologyInfo, states, state.name))
 	}
 
+	registry.MustRegister(collectorStatesMetrics(states))
+	registry.MustRegister(permissionErrorsMetrics(ctx, states))
+
+	return registry
+}
+
+// collectorState tells if a collector must be registered for the current scrape.
+type collectorState struct {
+	name    string
+	enabled bool
+}
+
+// collectorStates resolves CollectAll, the node type and the individual flags into the list of
+// known collectors and whether each one will run. makeRegistry builds the collectors from this
+// list and it is also exposed as mongodb_exporter_collector_enabled.
+func (e *Exporter) collectorStates(nodeType mongoDBNodeType, requestOpts Opts) []collectorState {
 	// Enable collectors like collstats and indexstats depending on the number of collections
 	// present in the database.
 	limitsOk := false
@@ -154,6 +731,7 @@ func (e *Exporter) makeRegistry(ctx context.Context, client *mongo.Client, topol
 		e.opts.EnableDiagnosticData = true
 		e.opts.EnableDBStats = true
 		e.opts.EnableDBStatsFreeStorage = true
+		e.opts.EnableInstanceTotals = true
 		e.opts.EnableCollStats = true
 		e.opts.EnableTopMetrics = true
 		e.opts.EnableReplicasetStatus = true
@@ -161,12 +739,31 @@ func (e *Exporter) makeRegistry(ctx context.Context, client *mongo.Client, topol
 		e.opts.EnableCurrentopMetrics = true
 		e.opts.EnableProfile = true
 		e.opts.EnableShards = true
+		e.opts.EnableGlobalLock = true
+		e.opts.EnableAsserts = true
+		e.opts.EnableStorageStats = true
+		e.opts.EnableMemoryStats = true
+		e.opts.EnableCursorStats = true
+		e.opts.EnableFlowControl = true
+		e.opts.EnableElectionStats = true
+		e.opts.EnableQueryExecutorStats = true
+		e.opts.EnableLockStats = true
+		e.opts.EnableTransactionStats = true
+		e.opts.EnableSecurityStats = true
+		e.opts.EnableMongos = true
+		e.opts.EnableReplMetrics = true
+		e.opts.EnableCommandMetrics = true
+		e.opts.EnableBalancerStats = true
+		e.opts.EnableTTLStats = true
+		// The users collector needs elevated privileges and getFreeMonitoringStatus is deprecated,
+		// so they are not enabled by CollectAll.
 	}
 
 	// arbiter only have isMaster privileges
 	if nodeType == typeArbiter {
 		e.opts.EnableDBStats = false
 		e.opts.EnableDBStatsFreeStorage = false
+		e.opts.EnableInstanceTotals = false
 		e.opts.EnableCollStats = false
 		e.opts.EnableTopMetrics = false
 		e.opts.EnableReplicasetStatus = false
@@ -174,67 +771,351 @@ func (e *Exporter) makeRegistry(ctx context.Context, client *mongo.Client, topol
 		e.opts.EnableCurrentopMetrics = false
 		e.opts.EnableProfile = false
 		e.opts.EnableShards = false
+		e.opts.EnableGlobalLock = false
+		e.opts.EnableAsserts = false
+		e.opts.EnableStorageStats = false
+		e.opts.EnableMemoryStats = false
+		e.opts.EnableCursorStats = false
+		e.opts.EnableFlowControl = false
+		e.opts.EnableElectionStats = false
+		e.opts.EnableQueryExecutorStats = false
+		e.opts.EnableLockStats = false
+		e.opts.EnableTransactionStats = false
+		e.opts.EnableSecurityStats = false
+		e.opts.EnableMongos = false
+		e.opts.EnableReplMetrics = false
+		e.opts.EnableCommandMetrics = false
+		e.opts.EnableBalancerStats = false
+		e.opts.EnableTTLStats = false
+		e.opts.EnableFreeMonitoringStats = false
+		e.opts.EnableUserStats = false
+		e.opts.FanOutToMembers = false
 	}
 
-	// If we manually set the collection names we want or auto discovery is set.
-	if (len(e.opts.CollStatsNamespaces) > 0 || e.opts.DiscoveringMode) && e.opts.EnableCollStats && limitsOk && requestOpts.EnableCollStats {
-		cc := newCollectionStatsCollector(ctx, client, e.opts.Logger,
-			e.opts.CompatibleMode, e.opts.DiscoveringMode,
-			topologyInfo, e.opts.CollStatsNamespaces)
-		registry.MustRegister(cc)
+	return []collectorState{
+		{
+			// If we manually set the collection names we want or auto discovery is set.
+			name: collectorCollStats,
+			enabled: (len(e.opts.CollStatsNamespaces) > 0 || e.opts.DiscoveringMode) &&
+				e.opts.EnableCollStats && limitsOk && requestOpts.EnableCollStats,
+		},
+		{
+			// If we manually set the collection names we want or auto discovery is set.
+			name: collectorIndexStats,
+			enabled: (len(e.opts.IndexStatsCollections) > 0 || e.opts.DiscoveringMode) &&
+				e.opts.EnableIndexStats && limitsOk && requestOpts.EnableIndexStats,
+		},
+		{
+			name:    collectorDiagnosticData,
+			enabled: e.opts.EnableDiagnosticData && requestOpts.EnableDiagnosticData,
+		},
+		{
+			name:    collectorDBStats,
+			enabled: e.opts.EnableDBStats && limitsOk && requestOpts.EnableDBStats,
+		},
+		{
+			name: collectorCurrentopMetrics,
+			enabled: e.opts.EnableCurrentopMetrics && nodeType != typeMongos && limitsOk &&
+				requestOpts.EnableCurrentopMetrics && e.opts.CurrentOpSlowTime != "",
+		},
+		{
+			name: collectorProfile,
+			enabled: e.opts.EnableProfile && nodeType != typeMongos && limitsOk &&
+				requestOpts.EnableProfile && e.opts.ProfileTimeTS != 0,
+		},
+		{
+			name:    collectorTopMetrics,
+			enabled: e.opts.EnableTopMetrics && nodeType != typeMongos && limitsOk && requestOpts.EnableTopMetrics,
+		},
+		{
+			// replSetGetStatus is not supported through mongos.
+			name:    collectorReplicasetStatus,
+			enabled: e.opts.EnableReplicasetStatus && nodeType != typeMongos && requestOpts.EnableReplicasetStatus,
+		},
+		{
+			name: collectorShards,
+			// Without mongos or the config servers, the config database of the connected node is
+			// read, so the chunks are only collected if the namespaces are explicitly listed.
+			enabled: e.opts.EnableShards && requestOpts.EnableShards &&
+				(nodeType == typeMongos || e.opts.ConfigServerURI != "" || len(removeEmptyStrings(e.opts.ShardCollStatsNamespaces)) > 0),
+		},
+		{
+			name:    collectorGlobalLock,
+			enabled: e.opts.EnableGlobalLock && requestOpts.EnableGlobalLock,
+		},
+		{
+			name: collectorAsserts,
+			// In compatible mode the diagnostic data collector already exposes mongodb_asserts_total
+			// with other labels.
+			enabled: e.opts.EnableAsserts && requestOpts.EnableAsserts &&
+				!(e.opts.compatibleMode(collectorDiagnosticData) && e.opts.EnableDiagnosticData && requestOpts.EnableDiagnosticData),
+		},
+		{
+			name:    collectorStorageStats,
+			enabled: e.opts.EnableStorageStats && nodeType != typeMongos && requestOpts.EnableStorageStats,
+		},
+		{
+			name:    collectorMemoryStats,
+			enabled: e.opts.EnableMemoryStats && requestOpts.EnableMemoryStats,
+		},
+		{
+			name:    collectorCursorStats,
+			enabled: e.opts.EnableCursorStats && requestOpts.EnableCursorStats,
+		},
+		{
+			name:    collectorFlowControl,
+			enabled: e.opts.EnableFlowControl && nodeType != typeMongos && requestOpts.EnableFlowControl,
+		},
+		{
+			name:    collectorElectionStats,
+			enabled: e.opts.EnableElectionStats && nodeType != typeMongos && requestOpts.EnableElectionStats,
+		},
+		{
+			name:    collectorQueryExecutorStats,
+			enabled: e.opts.EnableQueryExecutorStats && nodeType != typeMongos && requestOpts.EnableQueryExecutorStats,
+		},
+		{
+			name:    collectorLockStats,
+			enabled: e.opts.EnableLockStats && requestOpts.EnableLockStats,
+		},
+		{
+			name:    collectorTransactionStats,
+			enabled: e.opts.EnableTransactionStats && requestOpts.EnableTransactionStats,
+		},
+		{
+			name:    collectorSecurityStats,
+			enabled: e.opts.EnableSecurityStats && requestOpts.EnableSecurityStats,
+		},
+		{
+			name:    collectorMongos,
+			enabled: e.opts.EnableMongos && (nodeType == typeMongos || e.opts.ConfigServerURI != "") && requestOpts.EnableMongos,
+		},
+		{
+			name: collectorReplMetrics,
+			// In compatible mode the diagnostic data collector already exposes the buffer and the
+			// applied operations with the same names.
+			enabled: e.opts.EnableReplMetrics && nodeType != typeMongos && requestOpts.EnableReplMetrics &&
+				!(e.opts.compatibleMode(collectorDiagnosticData) && e.opts.EnableDiagnosticData && requestOpts.EnableDiagnosticData),
+		},
+		{
+			name:    collectorCommandMetrics,
+			enabled: e.opts.EnableCommandMetrics && requestOpts.EnableCommandMetrics,
+		},
+		{
+			name:    collectorBalancer,
+			enabled: e.opts.EnableBalancerStats && (nodeType == typeMongos || e.opts.ConfigServerURI != "") && requestOpts.EnableBalancerStats,
+		},
+		{
+			name:    collectorMembers,
+			enabled: e.opts.FanOutToMembers && nodeType != typeMongos && !strings.HasPrefix(e.opts.URI, "mongodb+srv://") && requestOpts.FanOutToMembers,
+		},
+		{
+			name:    collectorTTL,
+			enabled: e.opts.EnableTTLStats && nodeType != typeMongos && requestOpts.EnableTTLStats,
+		},
+		{
+			name:    collectorAtlas,
+			enabled: e.opts.atlasEnabled() && requestOpts.AtlasGroupID != "",
+		},
+		{
+			name:    collectorFreeMonitoring,
+			enabled: e.opts.EnableFreeMonitoringStats && nodeType != typeMongos && requestOpts.EnableFreeMonitoringStats,
+		},
+		{
+			name:    collectorUsers,
+			enabled: e.opts.EnableUserStats && requestOpts.EnableUserStats,
+		},
+		{
+			name: collectorCustomQueries,
+			// Arbiters don't hold any data.
+			enabled: len(e.customQueries) > 0 && nodeType != typeArbiter && requestOpts.CustomQueryFile != "",
+		},
+		{
+			name:    collectorShardReplLag,
+			enabled: e.opts.EnableShardReplLag && e.opts.FanOutToMembers && nodeType == typeMongos && requestOpts.EnableShardReplLag,
+		},
 	}
+}
 
-	// If we manually set the collection names we want or auto discovery is set.
-	if (len(e.opts.IndexStatsCollections) > 0 || e.opts.DiscoveringMode) && e.opts.EnableIndexStats && limitsOk && requestOpts.EnableIndexStats {
-		ic := newIndexStatsCollector(ctx, client, e.opts.Logger,
-			e.opts.DiscoveringMode, e.opts.EnableOverrideDescendingIndex,
-			topologyInfo, e.opts.IndexStatsCollections)
-		registry.MustRegister(ic)
-	}
+// stableAPICollectors are the collectors only running commands of the Stable API version 1, like
+// find, aggregate or count. The others would fail on every scrape in strict mode.
+var stableAPICollectors = map[string]struct{}{ //nolint:gochecknoglobals
+	collectorProfile:  {},
+	collectorShards:   {},
+	collectorMongos:   {},
+	collectorBalancer: {},
+	collectorAtlas:    {},
+}
 
-	if e.opts.EnableDiagnosticData && requestOpts.EnableDiagnosticData {
-		ddc := newDiagnosticDataCollector(ctx, client, e.opts.Logger,
-			e.opts.CompatibleMode, topologyInfo)
-		registry.MustRegister(ddc)
-	}
+// stableAPIStates disables the collectors which cannot run with the Stable API in strict mode.
+func (e *Exporter) stableAPIStates(states []collectorState) []collectorState {
+	for i, state := range states {
+		if _, ok := stableAPICollectors[state.name]; ok || !state.enabled {
+			continue
+		}
 
-	if e.opts.EnableDBStats && limitsOk && requestOpts.EnableDBStats {
-		cc := newDBStatsCollector(ctx, client, e.opts.Logger,
-			e.opts.CompatibleMode, topologyInfo, nil, e.opts.EnableDBStatsFreeStorage)
-		registry.MustRegister(cc)
+		e.logger.Debugf("The %s collector is skipped since it uses commands outside the Stable API", state.name)
+		states[i].enabled = false
 	}
 
-	if e.opts.EnableCurrentopMetrics && nodeType != typeMongos && limitsOk && requestOpts.EnableCurrentopMetrics && e.opts.CurrentOpSlowTime != "" {
-		coc := newCurrentopCollector(ctx, client, e.opts.Logger,
-			e.opts.CompatibleMode, topologyInfo, e.opts.CurrentOpSlowTime)
-		registry.MustRegister(coc)
-	}
+	return states
+}
 
-	if e.opts.EnableProfile && nodeType != typeMongos && limitsOk && requestOpts.EnableProfile && e.opts.ProfileTimeTS != 0 {
-		pc := newProfileCollector(ctx, client, e.opts.Logger,
-			e.opts.CompatibleMode, topologyInfo, e.opts.ProfileTimeTS)
-		registry.MustRegister(pc)
+// prioritizeCollectors returns the states sorted following the priority list. Collectors not
+// in the list keep their relative order after the listed ones. Unknown names are ignored.
+func prioritizeCollectors(states []collectorState, priority []string) []collectorState {
+	if len(priority) == 0 {
+		return states
 	}
 
-	if e.opts.EnableTopMetrics && nodeType != typeMongos && limitsOk && requestOpts.EnableTopMetrics {
-		tc := newTopCollector(ctx, client, e.opts.Logger,
-			e.opts.CompatibleMode, topologyInfo)
-		registry.MustRegister(tc)
+	rank := make(map[string]int, len(priority))
+	for i, name := range priority {
+		if _, ok := rank[name]; !ok {
+			rank[name] = i
+		}
 	}
 
-	// replSetGetStatus is not supported through mongos.
-	if e.opts.EnableReplicasetStatus && nodeType != typeMongos && requestOpts.EnableReplicasetStatus {
-		rsgsc := newReplicationSetStatusCollector(ctx, client, e.opts.Logger,
-			e.opts.CompatibleMode, topologyInfo)
-		registry.MustRegister(rsgsc)
+	sorted := make([]collectorState, len(states))
+	copy(sorted, states)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ri, iok := rank[sorted[i].name]
+		rj, jok := rank[sorted[j].name]
+		if iok && jok {
+			return ri < rj
+		}
+
+		return iok && !jok
+	})
+
+	return sorted
+}
+
+// collectorEnabled returns true if the collector having the given name is enabled in states.
+func collectorEnabled(states []collectorState, name string) bool {
+	for _, state := range states {
+		if state.name == name {
+			return state.enabled
+		}
 	}
 
-	if e.opts.EnableShards && requestOpts.EnableShards {
-		sc := newShardsCollector(ctx, client, e.opts.Logger, e.opts.CompatibleMode)
-		registry.MustRegister(sc)
+	return false
+}
+
+// newCollector builds the collector having the given name. states are the collectors of the scrape,
+// since some metrics are only exposed if another collector doesn't expose them already.
+func (e *Exporter) newCollector(ctx context.Context, client *mongo.Client, topologyInfo labelsGetter, states []collectorState, name string) prometheus.Collector {
+	switch name {
+	case collectorCollStats:
+		c := newCollectionStatsCollector(ctx, client, e.opts.Logger,
+			e.opts.compatibleMode(name), e.opts.DiscoveringMode,
+			topologyInfo, e.opts.CollStatsNamespaces, e.opts.CollStatsConcurrency)
+		c.wiredTiger = e.opts.EnableCollectionWiredTiger
+		c.indexSizes = e.opts.EnableIndexSizes
+		c.topN = e.opts.CollStatsTopN
+
+		return c
+	case collectorIndexStats:
+		return newIndexStatsCollector(ctx, client, e.opts.Logger,
+			e.opts.DiscoveringMode, e.opts.EnableOverrideDescendingIndex,
+			topologyInfo, e.opts.IndexStatsCollections)
+	case collectorDiagnosticData:
+		c := newDiagnosticDataCollector(ctx, client, e.opts.Logger,
+			e.opts.compatibleMode(name), topologyInfo)
+		c.paths = e.opts.DiagnosticDataPaths
+
+		return c
+	case collectorDBStats:
+		c := newDBStatsCollector(ctx, client, e.opts.Logger,
+			e.opts.compatibleMode(name), topologyInfo, nil, e.opts.EnableDBStatsFreeStorage)
+		c.instanceTotals = e.opts.EnableInstanceTotals
+		c.totalsExclude = e.opts.InstanceTotalsExcludeDBs
+
+		return c
+	case collectorCurrentopMetrics:
+		return newCurrentopCollector(ctx, client, e.opts.Logger,
+			e.opts.compatibleMode(name), topologyInfo, e.opts.CurrentOpSlowTime)
+	case collectorProfile:
+		return newProfileCollector(ctx, client, e.opts.Logger,
+			e.opts.compatibleMode(name), topologyInfo, e.opts.ProfileTimeTS)
+	case collectorTopMetrics:
+		return newTopCollector(ctx, client, e.opts.Logger,
+			e.opts.compatibleMode(name), topologyInfo)
+	case collectorReplicasetStatus:
+		c := newReplicationSetStatusCollector(ctx, client, e.opts.Logger,
+			e.opts.compatibleMode(name), topologyInfo)
+		c.diagnosticDataCompat = e.opts.compatibleMode(collectorDiagnosticData) && collectorEnabled(states, collectorDiagnosticData)
+
+		return c
+	case collectorShards:
+		configClient, err := e.getConfigClient(ctx)
+		if err != nil {
+			e.logger.Errorf("Cannot connect to the config servers, using the main connection: %v", err)
+		}
+
+		return newShardsCollector(ctx, client, configClient, e.opts.Logger, e.opts.compatibleMode(name), e.opts.ShardCollStatsNamespaces)
+	case collectorGlobalLock:
+		return newGlobalLockCollector(ctx, client, e.opts.Logger)
+	case collectorAsserts:
+		return newAssertsCollector(ctx, client, e.opts.Logger)
+	case collectorStorageStats:
+		return newStorageStatsCollector(ctx, client, e.opts.Logger)
+	case collectorMemoryStats:
+		return newMemoryCollector(ctx, client, e.opts.Logger)
+	case collectorCursorStats:
+		return newCursorCollector(ctx, client, e.opts.Logger)
+	case collectorFlowControl:
+		return newFlowControlCollector(ctx, client, e.opts.Logger)
+	case collectorElectionStats:
+		return newElectionCollector(ctx, client, e.opts.Logger)
+	case collectorQueryExecutorStats:
+		return newQueryExecutorCollector(ctx, client, e.opts.Logger)
+	case collectorLockStats:
+		return newLocksCollector(ctx, client, e.opts.Logger)
+	case collectorTransactionStats:
+		return newTransactionsCollector(ctx, client, e.opts.Logger)
+	case collectorSecurityStats:
+		return newSecurityCollector(ctx, client, e.opts.Logger)
+	case collectorMongos:
+		configClient, err := e.getConfigClient(ctx)
+		if err != nil {
+			e.logger.Errorf("Cannot connect to the config servers, using the main connection: %v", err)
+		}
+
+		return newMongosCollector(ctx, client, configClient, e.opts.Logger, e.opts.MongosStaleThreshold)
+	case collectorReplMetrics:
+		return newReplMetricsCollector(ctx, client, e.opts.Logger)
+	case collectorCommandMetrics:
+		return newCommandsCollector(ctx, client, e.opts.Logger, e.opts.CommandMetricsAllowlist)
+	case collectorBalancer:
+		configClient, err := e.getConfigClient(ctx)
+		if err != nil {
+			e.logger.Errorf("Cannot connect to the config servers, using the main connection: %v", err)
+		}
+
+		return newBalancerCollector(ctx, client, configClient, e.opts.Logger)
+	case collectorMembers:
+		return newMembersCollector(ctx, client, e.opts.Logger, e.memberServerStatus)
+	case collectorTTL:
+		return newTTLCollector(ctx, client, e.opts.Logger)
+	case collectorAtlas:
+		return newAtlasCollector(ctx, client, e.opts.Logger, defaultAtlasAPIURL,
+			e.opts.AtlasGroupID, e.opts.AtlasAPIPublicKey, e.opts.AtlasAPIPrivateKey)
+	case collectorFreeMonitoring:
+		return newFreeMonitoringCollector(ctx, client, e.opts.Logger)
+	case collectorUsers:
+		return newUsersCollector(ctx, client, e.opts.Logger)
+	case collectorCustomQueries:
+		return newCustomQueryCollector(ctx, client, e.opts.Logger, topologyInfo, e.customQueries)
+	case collectorShardReplLag:
+		configClient, err := e.getConfigClient(ctx)
+		if err != nil {
+			e.logger.Errorf("Cannot connect to the config servers, using the main connection: %v", err)
+		}
+
+		return newShardReplLagCollector(ctx, client, configClient, e.opts.Logger, e.shardReplSetStatus)
 	}
 
-	return registry
+	panic(fmt.Sprintf("unknown collector %q", name))
 }
 
 func (e *Exporter) getClient(ctx context.Context) (*mongo.Client, error) {
@@ -245,15 +1126,25 @@ func (e *Exporter) getClient(ctx context.Context) (*mongo.Client, error) {
 		defer e.clientMu.Unlock()
 
 		// If client is already initialized, return it.
-		if e.client != nil {
+		if e.client != nil && e.clientHealthy {
 			return e.client, nil
 		}
 
+		// The last scrape could not ping the server with this client, for example because the
+		// server was replaced. Drop it so a new one is built.
+		if e.client != nil {
+			e.disconnectAsync(e.client)
+			e.client = nil
+			// The new client may reach another topology.
+			e.discovery.newGeneration()
+		}
+
 		client, err := connect(context.Background(), e.opts)
 		if err != nil {
 			return nil, err
 		}
 		e.client = client
+		e.clientHealthy = true
 
 		return client, nil
 	}
@@ -267,55 +1158,85 @@ func (e *Exporter) getClient(ctx context.Context) (*mongo.Client, error) {
 	return client, nil
 }
 
+// disconnectTimeout bounds how long a replaced client waits for the operations still using it.
+const disconnectTimeout = 10 * time.Second
+
+// disconnectAsync disconnects a client which was replaced. It doesn't wait, so neither the scrape
+// rebuilding the client nor the lock are held while the operations still using the client end.
+func (e *Exporter) disconnectAsync(client *mongo.Client) {
+	if client == nil {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), disconnectTimeout)
+		defer cancel()
+
+		if err := client.Disconnect(ctx); err != nil {
+			e.logger.Warnf("Cannot disconnect the replaced client: %v", err)
+		}
+	}()
+}
+
+// markClientUnhealthy makes the next scrape rebuild the global connection pool client if it is
+// still the given client.
+func (e *Exporter) markClientUnhealthy(client *mongo.Client) {
+	e.clientMu.Lock()
+	defer e.clientMu.Unlock()
+
+	if client != nil && client == e.client {
+		e.clientHealthy = false
+	}
+}
+
+// getConfigClient returns the client connected to the config servers. It returns nil if
+// ConfigServerURI is not set. The client is kept for the next scrapes until Shutdown is called.
+func (e *Exporter) getConfigClient(ctx context.Context) (*mongo.Client, error) {
+	if e.opts.ConfigServerURI == "" {
+		return nil, nil
+	}
+
+	e.clientMu.Lock()
+	defer e.clientMu.Unlock()
+
+	if e.configClient != nil {
+		return e.configClient, nil
+	}
+
+	clientOpts, err := configClientOptions(e.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := connectWithOptions(ctx, clientOpts)
+	if err != nil {
+		return nil, err
+	}
+	e.configClient = client
+
+	return client, nil
+}
+
 // Handler returns an http.Handler that serves metrics. Can be used instead of
 // run for hooking up custom HTTP servers.
 func (e *Exporter) Handler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		seconds, err := strconv.Atoi(r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds"))
-		// To support also older ones vmagents.
-		if err != nil {
-			seconds = 10
-		}
-		seconds -= e.opts.TimeoutOffset
+		defer e.scrapes.start()()
 
-		var client *mongo.Client
-		ctx, cancel := context.WithTimeout(r.Context(), time.Duration(seconds)*time.Second)
+		ctx, cancel := context.WithTimeout(r.Context(), e.opts.scrapeTimeout(r))
 		defer cancel()
 
-		filters := r.URL.Query()["collect[]"]
-
-		requestOpts := Opts{}
-
-		if len(filters) == 0 {
-			requestOpts = *e.opts
-		}
-
-		for _, filter := range filters {
-			switch filter {
-			case "diagnosticdata":
-				requestOpts.EnableDiagnosticData = true
-			case "replicasetstatus":
-				requestOpts.EnableReplicasetStatus = true
-			case "dbstats":
-				requestOpts.EnableDBStats = true
-			case "topmetrics":
-				requestOpts.EnableTopMetrics = true
-			case "currentopmetrics":
-				requestOpts.EnableCurrentopMetrics = true
-			case "indexstats":
-				requestOpts.EnableIndexStats = true
-			case "collstats":
-				requestOpts.EnableCollStats = true
-			case "profile":
-				requestOpts.EnableProfile = true
-			case "shards":
-				requestOpts.EnableShards = true
-			}
+		requestOpts, err := e.requestOptions(r.URL.Query()["collect[]"])
+		if err != nil {
+			e.scrapes.errors.Inc()
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
 		}
 
-		client, err = e.getClient(ctx)
-		if err != nil {
-			e.logger.Errorf("Cannot connect to MongoDB: %v", err)
+		client, connectErr := e.getClient(ctx)
+		if connectErr != nil {
+			e.logger.Errorf("Cannot connect to MongoDB: %v", connectErr)
 		}
 
 		if client != nil && e.getTotalCollectionsCount() <= 0 {
@@ -327,17 +1248,7 @@ func (e *Exporter) Handler() http.Handler {
 			}
 		}
 
-		// Close client after usage.
-		if !e.opts.GlobalConnPool {
-			defer func() {
-				if client != nil {
-					err := client.Disconnect(ctx)
-					if err != nil {
-						e.logger.Errorf("Cannot disconnect client: %v", err)
-					}
-				}
-			}()
-		}
+		defer e.releaseClient(ctx, client)
 
 		var gatherers prometheus.Gatherers
 
@@ -352,7 +1263,22 @@ func (e *Exporter) Handler() http.Handler {
 		}
 
 		registry := e.makeRegistry(ctx, client, ti, requestOpts)
-		gatherers = append(gatherers, registry)
+		gatherers = append(gatherers, e.wrapGatherer(ctx, registry, connectErr))
+
+		if e.opts.FailScrapeOnDown {
+			// The metrics must be gathered before the status is written.
+			mfs, err := gatherers.Gather()
+			if mongodbDown(mfs) {
+				w = &statusWriter{ResponseWriter: w, status: http.StatusServiceUnavailable}
+			}
+			gatherers = prometheus.Gatherers{gatheredMetrics(mfs, err)}
+		}
+
+		if e.opts.OmitHelpText {
+			compactHandler(gatherers, e.logger).ServeHTTP(w, r)
+
+			return
+		}
 
 		// Delegate http serving to Prometheus client library, which will call collector.Collect.
 		h := promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{
@@ -364,19 +1290,178 @@ func (e *Exporter) Handler() http.Handler {
 	})
 }
 
+// wrapGatherer applies to the metrics of the collectors the processing shared by the scrapes and
+// the pushes: the help texts, the monotonic counters, the units normalization, the scrape metrics,
+// the target name and the label values length limit. connectErr is the error of the connection
+// to MongoDB, if any, counted as a scrape error.
+func (e *Exporter) wrapGatherer(ctx context.Context, registry prometheus.Gatherer, connectErr error) prometheus.Gatherer {
+	if e.helpTexts != nil {
+		registry = e.helpTexts.gatherer(registry)
+	}
+	if e.opts.MonotonicCounters && e.counters != nil {
+		registry = e.counters.gatherer(registry, e.opts.URI)
+	}
+	// After the counters since they look for the uptime in milliseconds.
+	if e.opts.NormalizeUnits {
+		registry = normalizeUnits(registry)
+	}
+	// The scrape metrics are gathered last so the errors of this scrape are already counted.
+	registry = prometheus.Gatherers{e.scrapes.countErrors(registry, connectErr), e.scrapes.registry}
+	if name, ok := targetName(ctx); ok {
+		if name == "" {
+			name = hostFromURI(e.opts.URI)
+		}
+		registry = &targetNameGatherer{gatherer: registry, name: sanitizeTargetName(name)}
+	}
+
+	return limitLabelValues(registry, e.opts.MaxLabelValueLength)
+}
+
+// requestOptions returns the collectors requested by the collect[] filters of a scrape. Without
+// filters, the configured collectors are used. Collectors must also be enabled in the options
+// of the exporter to run.
+func (e *Exporter) requestOptions(filters []string) (Opts, error) {
+	if len(filters) == 0 {
+		return *e.opts, nil
+	}
+
+	requestOpts := Opts{}
+
+	for _, filter := range filters {
+		switch filter {
+		case collectorDiagnosticData:
+			requestOpts.EnableDiagnosticData = true
+		case collectorReplicasetStatus:
+			requestOpts.EnableReplicasetStatus = true
+		case collectorDBStats:
+			requestOpts.EnableDBStats = true
+		case collectorTopMetrics:
+			requestOpts.EnableTopMetrics = true
+		case collectorCurrentopMetrics:
+			requestOpts.EnableCurrentopMetrics = true
+		case collectorIndexStats:
+			requestOpts.EnableIndexStats = true
+		case collectorCollStats:
+			requestOpts.EnableCollStats = true
+		case collectorProfile:
+			requestOpts.EnableProfile = true
+		case collectorShards:
+			requestOpts.EnableShards = true
+		case collectorGlobalLock:
+			requestOpts.EnableGlobalLock = true
+		case collectorAsserts:
+			requestOpts.EnableAsserts = true
+		case collectorStorageStats:
+			requestOpts.EnableStorageStats = true
+		case collectorMemoryStats:
+			requestOpts.EnableMemoryStats = true
+		case collectorCursorStats:
+			requestOpts.EnableCursorStats = true
+		case collectorFlowControl:
+			requestOpts.EnableFlowControl = true
+		case collectorElectionStats:
+			requestOpts.EnableElectionStats = true
+		case collectorQueryExecutorStats:
+			requestOpts.EnableQueryExecutorStats = true
+		case collectorLockStats:
+			requestOpts.EnableLockStats = true
+		case collectorTransactionStats:
+			requestOpts.EnableTransactionStats = true
+		case collectorSecurityStats:
+			requestOpts.EnableSecurityStats = true
+		case collectorMongos:
+			requestOpts.EnableMongos = true
+		case collectorReplMetrics:
+			requestOpts.EnableReplMetrics = true
+		case collectorCommandMetrics:
+			requestOpts.EnableCommandMetrics = true
+		case collectorBalancer:
+			requestOpts.EnableBalancerStats = true
+		case collectorTTL:
+			requestOpts.EnableTTLStats = true
+		case collectorFreeMonitoring:
+			requestOpts.EnableFreeMonitoringStats = true
+		case collectorUsers:
+			requestOpts.EnableUserStats = true
+		case collectorShardReplLag:
+			requestOpts.EnableShardReplLag = true
+		case collectorAtlas:
+			// The collector is enabled by the Atlas project, there is no flag for it.
+			requestOpts.AtlasGroupID = e.opts.AtlasGroupID
+		case collectorCustomQueries:
+			// The collector is enabled by the query file, there is no flag for it.
+			requestOpts.CustomQueryFile = e.opts.CustomQueryFile
+		case collectorMembers:
+			requestOpts.FanOutToMembers = true
+		case collectorUp:
+			// mongodb_up is always exposed.
+		default:
+			return Opts{}, fmt.Errorf("%w: %q", errUnknownCollector, filter)
+		}
+	}
+
+	return requestOpts, nil
+}
+
+// BasicHandler returns an http handler serving only mongodb_up and mongodb_exporter_build_info.
+// None of the collectors is run so it can be exposed where the full metrics are not allowed.
+func (e *Exporter) BasicHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), e.opts.scrapeTimeout(r))
+		defer cancel()
+
+		client, err := e.getClient(ctx)
+		if err != nil {
+			e.logger.Errorf("Cannot connect to MongoDB: %v", err)
+		}
+		defer e.releaseClient(ctx, client)
+
+		var upHost string
+		if e.opts.UpHostLabel {
+			upHost = hostFromURI(e.opts.URI)
+		}
+
+		registry := prometheus.NewRegistry()
+		up := &upCollector{ctx: ctx, client: client, logger: e.logger, host: upHost}
+		if e.opts.GlobalConnPool {
+			up.onPingError = func() { e.markClientUnhealthy(client) }
+		}
+		registry.MustRegister(up)
+		registry.MustRegister(version.NewCollector("mongodb_exporter"))
+
+		h := promhttp.HandlerFor(registry, promhttp.HandlerOpts{
+			ErrorHandling: promhttp.ContinueOnError,
+			ErrorLog:      e.logger,
+		})
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+// releaseClient disconnects the client after a scrape unless the global connection pool is used.
+func (e *Exporter) releaseClient(ctx context.Context, client *mongo.Client) {
+	if e.opts.GlobalConnPool || client == nil {
+		return
+	}
+
+	if err := client.Disconnect(ctx); err != nil {
+		e.logger.Errorf("Cannot disconnect client: %v", err)
+	}
+}
+
 func connect(ctx context.Context, opts *Opts) (*mongo.Client, error) {
-	clientOpts, err := dsn_fix.ClientOptionsForDSN(opts.URI)
+	clientOpts, err := clientOptions(opts)
 	if err != nil {
-		return nil, fmt.Errorf("invalid dsn: %w", err)
+		return nil, err
 	}
 
-	clientOpts.SetDirect(opts.DirectConnect)
-	clientOpts.SetAppName("mongodb_exporter")
+	return connectWithOptions(ctx, clientOpts)
+}
 
-	if clientOpts.ConnectTimeout == nil {
-		connectTimeout := time.Duration(opts.ConnectTimeoutMS) * time.Millisecond
-		clientOpts.SetConnectTimeout(connectTimeout)
-		clientOpts.SetServerSelectionTimeout(connectTimeout)
+func connectWithOptions(ctx context.Context, clientOpts *options.ClientOptions) (*mongo.Client, error) {
+	// The driver panics if auto encryption is used without libmongocrypt support.
+	if clientOpts.AutoEncryptionOptions != nil && !cseEnabled {
+		return nil, errAutoEncryptionNotSupported
 	}
 
 	client, err := mongo.Connect(ctx, clientOpts)
@@ -393,3 +1478,363 @@ func connect(ctx context.Context, opts *Opts) (*mongo.Client, error) {
 
 	return client, nil
 }
+
+// memberServerStatus runs serverStatus on the replica set member at host through a short-lived direct
+// connection made with the exporter options. It cannot be used with a mongodb+srv URI.
+func (e *Exporter) memberServerStatus(ctx context.Context, host string) (bson.M, error) {
+	clientOpts, err := clientOptions(e.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	clientOpts.SetHosts([]string{host}).SetDirect(true).SetMaxPoolSize(1)
+	if deadline, ok := ctx.Deadline(); ok {
+		clientOpts.SetServerSelectionTimeout(time.Until(deadline))
+	}
+
+	client, err := connectWithOptions(ctx, clientOpts)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Disconnect(ctx) //nolint:errcheck
+
+	// Not serverStatus since its result is shared by the collectors of the scrape.
+	return runServerStatus(ctx, client)
+}
+
+// shardReplSetStatus runs replSetGetStatus on the primary of the shard replica set through a
+// short-lived connection made with the exporter options, so the user must also exist on the shards.
+// host is the shard connection string of config.shards, like rs1/h1:27018,h2:27018.
+func (e *Exporter) shardReplSetStatus(ctx context.Context, host string) (bson.M, error) {
+	replicaSet, hosts := parseShardHost(host)
+	if replicaSet == "" {
+		return nil, fmt.Errorf("%w: %s", errShardNotReplicaSet, host)
+	}
+
+	clientOpts, err := clientOptions(e.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	clientOpts.SetHosts(hosts).SetReplicaSet(replicaSet).SetDirect(false).SetMaxPoolSize(1).
+		SetReadPreference(readpref.Primary())
+	if deadline, ok := ctx.Deadline(); ok {
+		clientOpts.SetServerSelectionTimeout(time.Until(deadline))
+	}
+
+	client, err := connectWithOptions(ctx, clientOpts)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Disconnect(ctx) //nolint:errcheck
+
+	var status bson.M
+	cmd := bson.D{{Key: "replSetGetStatus", Value: 1}}
+	if err := client.Database("admin").RunCommand(ctx, cmd).Decode(&status); err != nil {
+		return nil, err
+	}
+
+	return status, nil
+}
+
+// clientOptions builds the driver options from the URI and the exporter options.
+func clientOptions(opts *Opts) (*options.ClientOptions, error) {
+	// Check it before parsing the URI since parsing a +srv URI has to resolve the SRV record.
+	if opts.DirectConnect && strings.HasPrefix(opts.URI, "mongodb+srv://") {
+		return nil, errDirectConnectWithSRV
+	}
+
+	if opts.BypassAutoEncryption != (opts.KeyVaultNamespace != "") {
+		return nil, errAutoEncryptionOptions
+	}
+
+	uri, err := expandURI(opts.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	uri, err = withDriverOptions(uri, opts.DriverOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	clientOpts, err := dsn_fix.ClientOptionsForDSN(uri)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dsn: %w", err)
+	}
+
+	clientOpts.SetDirect(opts.DirectConnect)
+	clientOpts.SetMonitor(permissionErrorsMonitor)
+
+	switch {
+	case opts.AppName != "":
+		clientOpts.SetAppName(opts.AppName)
+	case clientOpts.AppName == nil || *clientOpts.AppName == "":
+		clientOpts.SetAppName(defaultAppName)
+	}
+
+	if clientOpts.ConnectTimeout == nil {
+		clientOpts.SetConnectTimeout(time.Duration(opts.ConnectTimeoutMS) * time.Millisecond)
+	}
+
+	// The server selection timeout is, by order of precedence, ServerSelectionTimeoutMS, the
+	// serverSelectionTimeoutMS option of the URI and the scrape timeout. The driver default (30s)
+	// is longer than a scrape so, if there is no primary, scrapes would time out instead of
+	// reporting mongodb_up 0. The connect timeout only bounds the handshake with a server.
+	switch {
+	case opts.ServerSelectionTimeoutMS > 0:
+		clientOpts.SetServerSelectionTimeout(time.Duration(opts.ServerSelectionTimeoutMS) * time.Millisecond)
+	case clientOpts.ServerSelectionTimeout != nil && *clientOpts.ServerSelectionTimeout > 0:
+		// Set in the URI.
+	default:
+		clientOpts.SetServerSelectionTimeout(opts.scrapeTimeout(nil))
+	}
+
+	if opts.SocketTimeoutMS > 0 {
+		clientOpts.SetSocketTimeout(time.Duration(opts.SocketTimeoutMS) * time.Millisecond)
+	}
+
+	if opts.MaxConnIdleTimeMS > 0 {
+		clientOpts.SetMaxConnIdleTime(time.Duration(opts.MaxConnIdleTimeMS) * time.Millisecond)
+	}
+
+	if opts.SRVMaxHosts > 0 {
+		clientOpts.SetSRVMaxHosts(opts.SRVMaxHosts)
+	}
+
+	if opts.ReadPreference != "" || len(opts.ReadPreferenceTagSets) > 0 {
+		rp, err := readPreference(opts, clientOpts.ReadPreference)
+		if err != nil {
+			return nil, err
+		}
+		clientOpts.SetReadPreference(rp)
+	}
+
+	if opts.ReadConcern != "" {
+		rc, err := readConcern(opts.ReadConcern)
+		if err != nil {
+			return nil, err
+		}
+		clientOpts.SetReadConcern(rc)
+	}
+
+	// Only the primary can serve linearizable reads.
+	if clientOpts.ReadConcern != nil && clientOpts.ReadConcern.Level == readconcern.Linearizable().Level &&
+		clientOpts.ReadPreference != nil && clientOpts.ReadPreference.Mode() != readpref.PrimaryMode {
+		return nil, errLinearizableRead
+	}
+
+	if opts.HeartbeatInterval > 0 {
+		clientOpts.SetHeartbeatInterval(opts.HeartbeatInterval)
+	}
+
+	if opts.ServerAPIVersion != "" || opts.ServerAPIStrict {
+		serverAPI, err := serverAPIOptions(opts)
+		if err != nil {
+			return nil, err
+		}
+		clientOpts.SetServerAPIOptions(serverAPI)
+	}
+
+	if opts.BypassAutoEncryption {
+		clientOpts.SetAutoEncryptionOptions(options.AutoEncryption().
+			SetKeyVaultNamespace(opts.KeyVaultNamespace).
+			SetBypassAutoEncryption(true).
+			// The driver requires a KMS provider even if nothing is encrypted. Since encryption
+			// is bypassed, the local master key is never used.
+			SetKmsProviders(map[string]map[string]interface{}{
+				"local": {"key": make([]byte, localMasterKeySize)},
+			}))
+	}
+
+	return clientOpts, nil
+}
+
+// readPreference returns the read preference with the mode and the tag sets of the options.
+// uriPref is the read preference of the URI, if any, which provides the defaults.
+func readPreference(opts *Opts, uriPref *readpref.ReadPref) (*readpref.ReadPref, error) {
+	mode := readpref.PrimaryMode
+	var prefOpts []readpref.Option
+
+	if uriPref != nil {
+		mode = uriPref.Mode()
+		if maxStaleness, ok := uriPref.MaxStaleness(); ok {
+			prefOpts = append(prefOpts, readpref.WithMaxStaleness(maxStaleness))
+		}
+	}
+
+	if opts.ReadPreference != "" {
+		var err error
+		if mode, err = readpref.ModeFromString(opts.ReadPreference); err != nil {
+			return nil, fmt.Errorf("invalid read preference: %w", err)
+		}
+	}
+
+	if len(opts.ReadPreferenceTagSets) > 0 {
+		if mode == readpref.PrimaryMode {
+			return nil, errReadPreferenceTags
+		}
+		prefOpts = append(prefOpts, readpref.WithTagSets(tag.NewTagSetsFromMaps(opts.ReadPreferenceTagSets)...))
+	} else if uriPref != nil && len(uriPref.TagSets()) > 0 && mode != readpref.PrimaryMode {
+		prefOpts = append(prefOpts, readpref.WithTagSets(uriPref.TagSets()...))
+	}
+
+	rp, err := readpref.New(mode, prefOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid read preference: %w", err)
+	}
+
+	return rp, nil
+}
+
+// readConcern returns the read concern of the level.
+func readConcern(level string) (*readconcern.ReadConcern, error) {
+	switch level {
+	case "local":
+		return readconcern.Local(), nil
+	case "majority":
+		return readconcern.Majority(), nil
+	case "linearizable":
+		return readconcern.Linearizable(), nil
+	case "snapshot":
+		return readconcern.Snapshot(), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", errReadConcern, level)
+	}
+}
+
+// reservedDriverOptions are the connection string options, in lower case, which cannot be set
+// with Opts.DriverOptions. They are either managed by other fields or disable the TLS verification.
+var reservedDriverOptions = map[string]struct{}{ //nolint:gochecknoglobals
+	"appname":                              {},
+	"directconnection":                     {},
+	"connect":                              {},
+	"connecttimeoutms":                     {},
+	"serverselectiontimeoutms":             {},
+	"heartbeatfrequencyms":                 {},
+	"srvmaxhosts":                          {},
+	"readpreference":                       {},
+	"readpreferencetags":                   {},
+	"readconcernlevel":                     {},
+	"tlsinsecure":                          {},
+	"tlsallowinvalidcertificates":          {},
+	"tlsallowinvalidhostnames":             {},
+	"tlsdisablecertificaterevocationcheck": {},
+	"tlsdisableocspendpointcheck":          {},
+	"sslallowinvalidcertificates":          {},
+	"sslallowinvalidhostnames":             {},
+}
+
+// uriVariable matches the ${NAME} placeholders of the URI.
+var uriVariable = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`) //nolint:gochecknoglobals
+
+// expandURI replaces the ${NAME} placeholders of the URI by the value of the environment variables
+// so secrets, like the password, don't have to be in the flags. The values replacing placeholders
+// of the user name and the password are percent-encoded, so they can have any character, but then
+// the user name and the password must be separate placeholders. A $ not followed by a placeholder
+// is kept as is. The expanded URI must never be logged since it may contain the secrets.
+func expandURI(uri string) (string, error) {
+	var unset []string
+
+	expand := func(s string, escape bool) string {
+		return uriVariable.ReplaceAllStringFunc(s, func(placeholder string) string {
+			name := uriVariable.FindStringSubmatch(placeholder)[1]
+
+			value, ok := os.LookupEnv(name)
+			if !ok {
+				unset = append(unset, name)
+			}
+
+			if escape {
+				// The driver unescapes the user name and the password with url.PathUnescape,
+				// which doesn't decode + as a space.
+				value = strings.ReplaceAll(url.QueryEscape(value), "+", "%20")
+			}
+
+			return value
+		})
+	}
+
+	var expanded string
+	scheme, rest, ok := strings.Cut(uri, "://")
+	if userinfo, hosts, found := cutUserinfo(rest); ok && found {
+		expanded = expand(scheme, false) + "://" + expand(userinfo, true) + "@" + expand(hosts, false)
+	} else {
+		expanded = expand(uri, false)
+	}
+
+	if len(unset) > 0 {
+		return "", fmt.Errorf("%w: %s", errUnsetURIVariable, strings.Join(unset, ", "))
+	}
+
+	return expanded, nil
+}
+
+// serverAPIOptions returns the Stable API options of the exporter options.
+func serverAPIOptions(opts *Opts) (*options.ServerAPIOptions, error) {
+	if opts.ServerAPIVersion == "" {
+		return nil, errServerAPIStrict
+	}
+
+	version := options.ServerAPIVersion(opts.ServerAPIVersion)
+	if err := version.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", errServerAPIVersion, opts.ServerAPIVersion)
+	}
+
+	return options.ServerAPI(version).SetStrict(opts.ServerAPIStrict), nil
+}
+
+// withDriverOptions adds the driver options to the query of the URI. Since they are added after
+// the options already in the URI, they take precedence.
+func withDriverOptions(uri string, driverOpts map[string]string) (string, error) {
+	if len(driverOpts) == 0 {
+		return uri, nil
+	}
+
+	query := url.Values{}
+	for key, value := range driverOpts {
+		if _, ok := reservedDriverOptions[strings.ToLower(key)]; ok {
+			return "", fmt.Errorf("%w: %s", errReservedDriverOption, key)
+		}
+		query.Set(key, value)
+	}
+
+	switch {
+	case strings.Contains(uri, "?"):
+		return uri + "&" + query.Encode(), nil
+	case strings.Contains(strings.TrimPrefix(strings.TrimPrefix(uri, "mongodb+srv://"), "mongodb://"), "/"):
+		return uri + "?" + query.Encode(), nil
+	default:
+		// The driver requires a slash between the hosts and the options.
+		return uri + "/?" + query.Encode(), nil
+	}
+}
+
+// configClientOptions returns the options to connect to the config servers. The credentials and
+// the TLS configuration of the main URI are used if ConfigServerURI doesn't have its own.
+func configClientOptions(opts *Opts) (*options.ClientOptions, error) {
+	mainOpts, err := clientOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	cfgOpts := *opts
+	cfgOpts.URI = opts.ConfigServerURI
+	// The config servers are a replica set, they must be discovered.
+	cfgOpts.DirectConnect = false
+
+	clientOpts, err := clientOptions(&cfgOpts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config server URI: %w", err)
+	}
+
+	if clientOpts.Auth == nil {
+		clientOpts.Auth = mainOpts.Auth
+	}
+
+	if clientOpts.TLSConfig == nil {
+		clientOpts.TLSConfig = mainOpts.TLSConfig
+	}
+
+	return clientOpts, nil
+}
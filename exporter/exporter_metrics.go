@@ -19,6 +19,7 @@ import (
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 )
 
 // measureCollectTime measures time taken for scrape by collector
@@ -41,3 +42,74 @@ func measureCollectTime(ch chan<- prometheus.Metric, exporter, collector string)
 		ch <- scrapeMetric
 	}
 }
+
+// collectorStatesMetrics exposes whether each known collector is enabled for the scrape.
+// Disabled collectors are also reported, with a value of 0.
+func collectorStatesMetrics(states []collectorState) *prometheus.GaugeVec {
+	gv := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "mongodb_exporter_collector_enabled",
+		Help: "Whether the collector is enabled in the exporter configuration.",
+	}, []string{"collector"})
+
+	for _, state := range states {
+		var value float64
+		if state.enabled {
+			value = 1
+		}
+		gv.WithLabelValues(state.name).Set(value)
+	}
+
+	return gv
+}
+
+// scrapeMetrics counts the scrapes handled by the exporter. Unlike the collectors, they are
+// registered once, in a registry living as long as the exporter, so the counters persist
+// between scrapes.
+type scrapeMetrics struct {
+	registry   *prometheus.Registry
+	scrapes    prometheus.Counter
+	errors     prometheus.Counter
+	inProgress prometheus.Gauge
+}
+
+func newScrapeMetrics() *scrapeMetrics {
+	s := &scrapeMetrics{
+		registry: prometheus.NewRegistry(),
+		scrapes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "mongodb_exporter_scrapes_total",
+			Help: "Total number of scrapes handled by the exporter.",
+		}),
+		errors: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "mongodb_exporter_scrape_errors_total",
+			Help: "Total number of scrapes which could not connect to MongoDB, were invalid or failed to gather the metrics.",
+		}),
+		inProgress: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "mongodb_exporter_scrape_in_progress",
+			Help: "Number of scrapes being handled by the exporter.",
+		}),
+	}
+	s.registry.MustRegister(s.scrapes, s.errors, s.inProgress)
+
+	return s
+}
+
+// start counts a new scrape and returns the function to call when it is done.
+func (s *scrapeMetrics) start() func() {
+	s.scrapes.Inc()
+	s.inProgress.Inc()
+
+	return s.inProgress.Dec
+}
+
+// countErrors returns a gatherer counting a scrape error if connectErr is not nil or if
+// gathering the metrics of g fails.
+func (s *scrapeMetrics) countErrors(g prometheus.Gatherer, connectErr error) prometheus.Gatherer {
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		mfs, err := g.Gather()
+		if err != nil || connectErr != nil {
+			s.errors.Inc()
+		}
+
+		return mfs, err
+	})
+}
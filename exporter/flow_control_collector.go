@@ -0,0 +1,103 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type flowControlCollector struct {
+	ctx  context.Context
+	base *baseCollector
+}
+
+// newFlowControlCollector creates a collector for the flow control state reported by serverStatus.flowControl.
+func newFlowControlCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger) *flowControlCollector {
+	return &flowControlCollector{
+		ctx:  ctx,
+		base: newBaseCollector(client, logger),
+	}
+}
+
+func (d *flowControlCollector) Describe(ch chan<- *prometheus.Desc) {
+	d.base.Describe(d.ctx, ch, d.collect)
+}
+
+func (d *flowControlCollector) Collect(ch chan<- prometheus.Metric) {
+	d.base.Collect(ch)
+}
+
+func (d *flowControlCollector) collect(ch chan<- prometheus.Metric) {
+	defer measureCollectTime(ch, "mongodb", "flow_control")()
+
+	logger := d.base.logger
+
+	m, err := serverStatus(d.ctx, d.base.client)
+	if err != nil {
+		logger.Errorf("cannot get flow control stats: %s", err)
+
+		return
+	}
+
+	for _, metric := range flowControlMetrics(m) {
+		ch <- metric
+	}
+}
+
+// flowControlMetrics returns the flow control metrics from a serverStatus document.
+// Flow control is only available since MongoDB 4.2 so no metrics are returned for older versions.
+func flowControlMetrics(m bson.M) []prometheus.Metric {
+	fc, ok := m["flowControl"].(bson.M)
+	if !ok {
+		return nil
+	}
+
+	var metrics []prometheus.Metric
+	createMetric := func(field, name, help string, valueType prometheus.ValueType) {
+		var value float64
+		switch v := fc[field].(type) {
+		case bool:
+			if v {
+				value = 1
+			}
+		default:
+			f, err := asFloat64(v)
+			if err != nil || f == nil {
+				return
+			}
+			value = *f
+		}
+
+		d := prometheus.NewDesc(name, help, nil, nil)
+		metrics = append(metrics, prometheus.MustNewConstMetric(d, valueType, value))
+	}
+
+	createMetric("isLagged", "mongodb_flow_control_is_lagged",
+		"Whether flow control is engaged because the majority commit point is lagging.", prometheus.GaugeValue)
+	createMetric("targetRateLimit", "mongodb_flow_control_target_rate_limit",
+		"Maximum number of locks per second the primary allows writes to acquire.", prometheus.GaugeValue)
+	createMetric("timeAcquiringMicros", "mongodb_flow_control_time_acquiring_micros_total",
+		"Total time operations have waited to acquire a flow control ticket.", prometheus.CounterValue)
+
+	return metrics
+}
+
+var _ prometheus.Collector = (*flowControlCollector)(nil)
@@ -0,0 +1,57 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestFlowControlMetrics(t *testing.T) {
+	m := bson.M{
+		"flowControl": bson.M{
+			"enabled":             true,
+			"targetRateLimit":     int32(1000000000),
+			"timeAcquiringMicros": int64(1234),
+			"locksPerKiloOp":      float64(0),
+			"sustainerRate":       int32(0),
+			"isLagged":            true,
+			"isLaggedCount":       int32(2),
+			"isLaggedTimeMicros":  int64(1000),
+		},
+	}
+
+	expected := strings.NewReader(`
+	# HELP mongodb_flow_control_is_lagged Whether flow control is engaged because the majority commit point is lagging.
+	# TYPE mongodb_flow_control_is_lagged gauge
+	mongodb_flow_control_is_lagged 1
+	# HELP mongodb_flow_control_target_rate_limit Maximum number of locks per second the primary allows writes to acquire.
+	# TYPE mongodb_flow_control_target_rate_limit gauge
+	mongodb_flow_control_target_rate_limit 1e+09
+	# HELP mongodb_flow_control_time_acquiring_micros_total Total time operations have waited to acquire a flow control ticket.
+	# TYPE mongodb_flow_control_time_acquiring_micros_total counter
+	mongodb_flow_control_time_acquiring_micros_total 1234` + "\n")
+
+	err := testutil.CollectAndCompare(metricsCollector(flowControlMetrics(m)), expected)
+	assert.NoError(t, err)
+
+	// MongoDB < 4.2
+	assert.Empty(t, flowControlMetrics(bson.M{"ok": float64(1)}))
+}
@@ -0,0 +1,86 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type freeMonitoringCollector struct {
+	ctx  context.Context
+	base *baseCollector
+}
+
+// newFreeMonitoringCollector creates a collector for the state of the free cloud monitoring.
+func newFreeMonitoringCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger) *freeMonitoringCollector {
+	return &freeMonitoringCollector{
+		ctx:  ctx,
+		base: newBaseCollector(client, logger),
+	}
+}
+
+func (d *freeMonitoringCollector) Describe(ch chan<- *prometheus.Desc) {
+	d.base.Describe(d.ctx, ch, d.collect)
+}
+
+func (d *freeMonitoringCollector) Collect(ch chan<- prometheus.Metric) {
+	d.base.Collect(ch)
+}
+
+func (d *freeMonitoringCollector) collect(ch chan<- prometheus.Metric) {
+	defer measureCollectTime(ch, "mongodb", "freemonitoring")()
+
+	logger := d.base.logger
+
+	var m bson.M
+	cmd := bson.D{{Key: "getFreeMonitoringStatus", Value: 1}}
+	if err := d.base.client.Database("admin").RunCommand(d.ctx, cmd).Decode(&m); err != nil {
+		// Free monitoring was removed in MongoDB 7.0.
+		if isUnauthorized(err) || isCommandNotFound(err) {
+			logger.Debugf("cannot get the free monitoring status: %s", err)
+
+			return
+		}
+		logger.Errorf("cannot get the free monitoring status: %s", err)
+
+		return
+	}
+
+	if metric := freeMonitoringMetric(m); metric != nil {
+		ch <- metric
+	}
+}
+
+// freeMonitoringMetric returns mongodb_free_monitoring_state from a getFreeMonitoringStatus response,
+// with the state, like enabled, disabled or undecided, as label. It returns nil if there is no state.
+func freeMonitoringMetric(m bson.M) prometheus.Metric { //nolint:ireturn
+	state, ok := m["state"].(string)
+	if !ok || state == "" {
+		return nil
+	}
+
+	d := prometheus.NewDesc("mongodb_free_monitoring_state",
+		"The state of the free cloud monitoring of the node.", []string{"state"}, nil)
+
+	return prometheus.MustNewConstMetric(d, prometheus.GaugeValue, 1, state)
+}
+
+var _ prometheus.Collector = (*freeMonitoringCollector)(nil)
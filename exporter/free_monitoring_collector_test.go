@@ -0,0 +1,59 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestFreeMonitoringMetric(t *testing.T) {
+	m := bson.M{
+		"state":        "enabled",
+		"message":      "To see your monitoring data, navigate to the unique URL below.",
+		"url":          "https://cloud.mongodb.com/freemonitoring/cluster/XYZ",
+		"userReminder": "",
+		"ok":           float64(1),
+	}
+
+	expected := `
+	# HELP mongodb_free_monitoring_state The state of the free cloud monitoring of the node.
+	# TYPE mongodb_free_monitoring_state gauge
+	mongodb_free_monitoring_state{state="enabled"} 1` + "\n"
+
+	err := testutil.CollectAndCompare(metricsCollector([]prometheus.Metric{freeMonitoringMetric(m)}), strings.NewReader(expected))
+	assert.NoError(t, err)
+
+	assert.Nil(t, freeMonitoringMetric(bson.M{"ok": float64(1)}))
+
+	// MongoDB 7.0 doesn't have the command anymore.
+	assert.True(t, isCommandNotFound(mongo.CommandError{Code: 59, Name: "CommandNotFound"}))
+	assert.False(t, isCommandNotFound(mongo.CommandError{Code: 13, Name: "Unauthorized"}))
+}
+
+func TestFreeMonitoringNotInCollectAll(t *testing.T) {
+	opts := &Opts{CollectAll: true}
+	e := &Exporter{opts: opts, lock: &sync.Mutex{}}
+
+	assert.False(t, collectorEnabled(e.collectorStates(typeMongod, *opts), collectorFreeMonitoring))
+}
@@ -17,9 +17,12 @@ package exporter
 
 import (
 	"context"
+	"strings"
 
+	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
@@ -29,13 +32,35 @@ import (
 type generalCollector struct {
 	ctx  context.Context
 	base *baseCollector
+
+	host         string
+	replsetState bool
+	// onPingError, if set, is called when MongoDB cannot be pinged.
+	onPingError func()
+	// featureCompatibility exposes the feature compatibility version. It is not set for mongos.
+	featureCompatibility bool
 }
 
+const (
+	// unauthorized is the error code returned when the user lacks the privileges to run a command.
+	unauthorized = 13
+	// commandNotFound is the error code returned for a command the server doesn't have.
+	commandNotFound = 59
+	// apiStrictError is the error code returned for a command outside the Stable API in strict mode.
+	apiStrictError = 323
+)
+
 // newGeneralCollector creates a collector for MongoDB connectivity status.
-func newGeneralCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger) *generalCollector {
+// If host is not empty, mongodb_up will have the host label and an error label explaining
+// why the instance is down.
+// If replsetState is true, the replica set state of the node is also exposed.
+func newGeneralCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger, host string, replsetState bool) *generalCollector {
 	return &generalCollector{
 		ctx:  ctx,
 		base: newBaseCollector(client, logger),
+
+		host:         host,
+		replsetState: replsetState,
 	}
 }
 
@@ -49,23 +74,261 @@ func (d *generalCollector) Collect(ch chan<- prometheus.Metric) {
 
 func (d *generalCollector) collect(ch chan<- prometheus.Metric) {
 	defer measureCollectTime(ch, "mongodb", "general")()
-	ch <- mongodbUpMetric(d.ctx, d.base.client, d.base.logger)
+	ch <- mongodbUpMetric(d.ctx, d.base.client, d.base.logger, d.host, d.onPingError)
+
+	if d.base.client == nil {
+		return
+	}
+
+	if d.replsetState {
+		d.collectReplsetState(ch)
+	}
+
+	if d.featureCompatibility {
+		d.collectFeatureCompatibility(ch)
+	}
+}
+
+func (d *generalCollector) collectReplsetState(ch chan<- prometheus.Metric) {
+	// replSetGetStatus tells apart all the member states but it requires the clusterMonitor role
+	// and it is not in the Stable API, so isMaster is used if it fails.
+	var status bson.M
+	cmd := bson.D{{Key: "replSetGetStatus", Value: 1}}
+	if err := d.base.client.Database("admin").RunCommand(d.ctx, cmd).Decode(&status); err != nil {
+		d.base.logger.Debugf("cannot get the replica set state with replSetGetStatus, using isMaster: %s", err)
+	} else if metrics := replSetGetStatusStateMetrics(status); metrics != nil {
+		for _, metric := range metrics {
+			ch <- metric
+		}
+
+		return
+	}
+
+	var m bson.M
+	cmd = bson.D{{Key: "isMaster", Value: 1}}
+	if err := d.base.client.Database("admin").RunCommand(d.ctx, cmd).Decode(&m); err != nil {
+		if isAPIStrictError(err) {
+			d.base.logger.Debugf("cannot get the replica set state with the Stable API: %s", err)
+
+			return
+		}
+		d.base.logger.Errorf("cannot get the replica set state: %s", err)
+
+		return
+	}
+
+	for _, metric := range replsetStateMetrics(m) {
+		ch <- metric
+	}
 }
 
-func mongodbUpMetric(ctx context.Context, client *mongo.Client, log *logrus.Logger) prometheus.Metric {
+func (d *generalCollector) collectFeatureCompatibility(ch chan<- prometheus.Metric) {
+	var m bson.M
+	cmd := bson.D{{Key: "getParameter", Value: 1}, {Key: "featureCompatibilityVersion", Value: 1}}
+	if err := d.base.client.Database("admin").RunCommand(d.ctx, cmd).Decode(&m); err != nil {
+		if isUnauthorized(err) || isAPIStrictError(err) {
+			d.base.logger.Debugf("not allowed to get the feature compatibility version: %s", err)
+
+			return
+		}
+		d.base.logger.Errorf("cannot get the feature compatibility version: %s", err)
+
+		return
+	}
+
+	if metric := featureCompatibilityMetric(m); metric != nil {
+		ch <- metric
+	}
+}
+
+// upCollector exposes only mongodb_up, without the scrape time and the replica set state
+// exposed by the general collector.
+type upCollector struct {
+	ctx    context.Context
+	client *mongo.Client
+	logger *logrus.Logger
+	host   string
+	// onPingError, if set, is called when MongoDB cannot be pinged.
+	onPingError func()
+}
+
+// Describe sends no descriptor so the collector is unchecked and MongoDB is not pinged
+// when the collector is registered.
+func (c *upCollector) Describe(chan<- *prometheus.Desc) {}
+
+func (c *upCollector) Collect(ch chan<- prometheus.Metric) {
+	ch <- mongodbUpMetric(c.ctx, c.client, c.logger, c.host, c.onPingError)
+}
+
+func mongodbUpMetric(ctx context.Context, client *mongo.Client, log *logrus.Logger, host string, onPingError func()) prometheus.Metric {
 	var value float64
+	reason := "cannot_connect"
 
 	if client != nil {
-		if err := client.Ping(ctx, readpref.PrimaryPreferred()); err == nil {
+		if err := pingMongoDB(ctx, client); err == nil {
 			value = 1
+			reason = ""
 		} else {
 			log.Errorf("error while checking mongodb connection: %s. mongo_up is set to 0", err)
+			reason = pingErrorReason(err)
+
+			if onPingError != nil {
+				onPingError()
+			}
 		}
 	}
 
-	d := prometheus.NewDesc("mongodb_up", "Whether MongoDB is up.", nil, nil)
+	if host == "" {
+		d := prometheus.NewDesc("mongodb_up", "Whether MongoDB is up.", nil, nil)
+
+		return prometheus.MustNewConstMetric(d, prometheus.GaugeValue, value)
+	}
+
+	d := prometheus.NewDesc("mongodb_up", "Whether MongoDB is up.", []string{"host", "error"}, nil)
 
-	return prometheus.MustNewConstMetric(d, prometheus.GaugeValue, value)
+	return prometheus.MustNewConstMetric(d, prometheus.GaugeValue, value, host, reason)
 }
 
-var _ prometheus.Collector = (*generalCollector)(nil)
+// pingMongoDB checks the connection to MongoDB. A secondary can answer if there is no primary.
+func pingMongoDB(ctx context.Context, client *mongo.Client) error {
+	return client.Ping(ctx, readpref.PrimaryPreferred())
+}
+
+// replsetStateMetrics returns the member state of the connected node and whether it is the primary
+// from an isMaster response. Standalone instances and mongos are not replica set members so no
+// metrics are returned for them.
+func replsetStateMetrics(m bson.M) []prometheus.Metric {
+	set, ok := m["setName"].(string)
+	if !ok || set == "" {
+		return nil
+	}
+
+	var state float64
+	switch {
+	case m["ismaster"] == true:
+		state = PrimaryState
+	case m["secondary"] == true:
+		state = SecondaryState
+	case m["arbiterOnly"] == true:
+		state = ArbiterState
+	default:
+		// isMaster doesn't tell apart the other states like RECOVERING or ROLLBACK.
+		state = UnknownState
+	}
+
+	return memberStateMetrics(set, state)
+}
+
+// replSetGetStatusStateMetrics is like replsetStateMetrics but it uses the myState field of a
+// replSetGetStatus response. It returns nil if the response has no state.
+func replSetGetStatusStateMetrics(status bson.M) []prometheus.Metric {
+	set, ok := status["set"].(string)
+	if !ok || set == "" {
+		return nil
+	}
+
+	state, err := asFloat64(status["myState"])
+	if err != nil || state == nil {
+		return nil
+	}
+
+	return memberStateMetrics(set, *state)
+}
+
+func memberStateMetrics(set string, state float64) []prometheus.Metric {
+	var isPrimary float64
+	if state == PrimaryState {
+		isPrimary = 1
+	}
+
+	labels := map[string]string{"set": set}
+
+	return []prometheus.Metric{
+		prometheus.MustNewConstMetric(prometheus.NewDesc("mongodb_mongod_replset_my_state",
+			"An integer between 0 and 10 that represents the replica state of the current member",
+			nil, labels), prometheus.GaugeValue, state),
+		prometheus.MustNewConstMetric(prometheus.NewDesc("mongodb_mongod_replset_is_primary",
+			"Whether the current member is the primary of the replica set.",
+			nil, labels), prometheus.GaugeValue, isPrimary),
+	}
+}
+
+// featureCompatibilityMetric returns mongodb_fcv from a getParameter response or nil if it has
+// no feature compatibility version.
+func featureCompatibilityMetric(m bson.M) prometheus.Metric { //nolint:ireturn
+	fcv, ok := m["featureCompatibilityVersion"].(bson.M)
+	if !ok {
+		return nil
+	}
+
+	version, ok := fcv["version"].(string)
+	if !ok || version == "" {
+		return nil
+	}
+
+	d := prometheus.NewDesc("mongodb_fcv", "The feature compatibility version of the node.", []string{"version"}, nil)
+
+	return prometheus.MustNewConstMetric(d, prometheus.GaugeValue, 1, version)
+}
+
+// isUnauthorized returns true if the command failed because the user lacks the privileges to run it.
+func isUnauthorized(err error) bool {
+	var cmdErr mongo.CommandError
+
+	return errors.As(err, &cmdErr) && cmdErr.Code == unauthorized
+}
+
+// isCommandNotFound returns true if the server doesn't have the command, like the commands removed by
+// newer versions.
+func isCommandNotFound(err error) bool {
+	var cmdErr mongo.CommandError
+
+	return errors.As(err, &cmdErr) && cmdErr.Code == commandNotFound
+}
+
+// isAPIStrictError returns true if the command was rejected because it is not in the Stable API.
+func isAPIStrictError(err error) bool {
+	var cmdErr mongo.CommandError
+
+	return errors.As(err, &cmdErr) && cmdErr.Code == apiStrictError
+}
+
+// pingErrorReason returns a short reason, usable as a label value, for a failed ping.
+func pingErrorReason(err error) string {
+	if errors.Is(err, context.DeadlineExceeded) || mongo.IsTimeout(err) {
+		return "timeout"
+	}
+
+	return "ping_failed"
+}
+
+// hostFromURI returns the first host of a MongoDB URI or an empty string if it is not a valid
+// MongoDB URI. Credentials are never included. IPv6 addresses keep their brackets so the port
+// can be told apart, for example [::1]:27017. For mongodb+srv URIs, it is the SRV record name.
+// url.Parse cannot be used since it rejects seed lists like h1:27017,[::1]:27017.
+func hostFromURI(uri string) string {
+	var hosts string
+	switch {
+	case strings.HasPrefix(uri, "mongodb://"):
+		hosts = strings.TrimPrefix(uri, "mongodb://")
+	case strings.HasPrefix(uri, "mongodb+srv://"):
+		hosts = strings.TrimPrefix(uri, "mongodb+srv://")
+	default:
+		return ""
+	}
+
+	// The userinfo goes first since the password could contain a ? if it is not escaped.
+	_, hosts, _ = cutUserinfo(hosts)
+	if i := strings.IndexAny(hosts, "/?"); i >= 0 {
+		hosts = hosts[:i]
+	}
+
+	host, _, _ := strings.Cut(hosts, ",")
+
+	return host
+}
+
+var (
+	_ prometheus.Collector = (*generalCollector)(nil)
+	_ prometheus.Collector = (*upCollector)(nil)
+)
@@ -0,0 +1,98 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type globalLockCollector struct {
+	ctx  context.Context
+	base *baseCollector
+}
+
+// newGlobalLockCollector creates a collector for the lock queues reported by serverStatus.globalLock.
+func newGlobalLockCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger) *globalLockCollector {
+	return &globalLockCollector{
+		ctx:  ctx,
+		base: newBaseCollector(client, logger),
+	}
+}
+
+func (d *globalLockCollector) Describe(ch chan<- *prometheus.Desc) {
+	d.base.Describe(d.ctx, ch, d.collect)
+}
+
+func (d *globalLockCollector) Collect(ch chan<- prometheus.Metric) {
+	d.base.Collect(ch)
+}
+
+func (d *globalLockCollector) collect(ch chan<- prometheus.Metric) {
+	defer measureCollectTime(ch, "mongodb", "global_lock")()
+
+	logger := d.base.logger
+
+	m, err := serverStatus(d.ctx, d.base.client)
+	if err != nil {
+		logger.Errorf("cannot get global lock stats: %s", err)
+
+		return
+	}
+
+	for _, metric := range globalLockMetrics(m) {
+		ch <- metric
+	}
+}
+
+// globalLockMetrics returns the lock queue metrics from a serverStatus document.
+// Some servers, like mongos, don't report the globalLock section so no metrics are returned.
+func globalLockMetrics(m bson.M) []prometheus.Metric {
+	gl, ok := m["globalLock"].(bson.M)
+	if !ok {
+		return nil
+	}
+
+	var metrics []prometheus.Metric
+	createMetrics := func(section, name, help string) {
+		counts, ok := gl[section].(bson.M)
+		if !ok {
+			return
+		}
+
+		d := prometheus.NewDesc(name, help, []string{"type"}, nil)
+		for _, t := range []string{"readers", "writers", "total"} {
+			f, err := asFloat64(counts[t])
+			if err != nil || f == nil {
+				continue
+			}
+			metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.GaugeValue, *f, t))
+		}
+	}
+
+	createMetrics("currentQueue", "mongodb_global_lock_current_queue",
+		"Number of operations queued waiting for the global lock.")
+	createMetrics("activeClients", "mongodb_global_lock_active_clients",
+		"Number of connected clients performing read and write operations.")
+
+	return metrics
+}
+
+var _ prometheus.Collector = (*globalLockCollector)(nil)
@@ -0,0 +1,61 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestGlobalLockMetrics(t *testing.T) {
+	m := bson.M{
+		"globalLock": bson.M{
+			"totalTime": int64(123456789),
+			"currentQueue": bson.M{
+				"total":   int32(3),
+				"readers": int32(1),
+				"writers": int32(2),
+			},
+			"activeClients": bson.M{
+				"total":   int32(10),
+				"readers": int32(4),
+				"writers": int32(6),
+			},
+		},
+	}
+
+	expected := strings.NewReader(`
+	# HELP mongodb_global_lock_active_clients Number of connected clients performing read and write operations.
+	# TYPE mongodb_global_lock_active_clients gauge
+	mongodb_global_lock_active_clients{type="readers"} 4
+	mongodb_global_lock_active_clients{type="total"} 10
+	mongodb_global_lock_active_clients{type="writers"} 6
+	# HELP mongodb_global_lock_current_queue Number of operations queued waiting for the global lock.
+	# TYPE mongodb_global_lock_current_queue gauge
+	mongodb_global_lock_current_queue{type="readers"} 1
+	mongodb_global_lock_current_queue{type="total"} 3
+	mongodb_global_lock_current_queue{type="writers"} 2` + "\n")
+
+	err := testutil.CollectAndCompare(metricsCollector(globalLockMetrics(m)), expected)
+	assert.NoError(t, err)
+
+	// mongos doesn't have the globalLock section.
+	assert.Empty(t, globalLockMetrics(bson.M{"ok": float64(1)}))
+}
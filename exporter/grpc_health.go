@@ -0,0 +1,124 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// healthWatchInterval is how often MongoDB is pinged for the clients watching the health status.
+const healthWatchInterval = 5 * time.Second
+
+// grpcHealthServer implements the gRPC health checking protocol for service meshes. The overall
+// health, with an empty service name, is the only one known: SERVING if MongoDB can be pinged.
+type grpcHealthServer struct {
+	grpc_health_v1.UnimplementedHealthServer
+
+	e *Exporter
+	// done is closed when the exporter is shut down so the watchers return.
+	done <-chan struct{}
+}
+
+// startGRPCHealth listens on GRPCHealthAddr and serves the gRPC health service until Shutdown is called.
+func (e *Exporter) startGRPCHealth(done <-chan struct{}) error {
+	listener, err := net.Listen("tcp", e.opts.GRPCHealthAddr)
+	if err != nil {
+		return err
+	}
+
+	server := grpc.NewServer()
+	grpc_health_v1.RegisterHealthServer(server, &grpcHealthServer{e: e, done: done})
+
+	e.grpcServer = server
+	e.grpcListener = listener
+
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			e.logger.Errorf("gRPC health server stopped: %v", err)
+		}
+	}()
+
+	return nil
+}
+
+func (s *grpcHealthServer) Check(ctx context.Context, req *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	if req.GetService() != "" {
+		return nil, status.Errorf(codes.NotFound, "unknown service %q", req.GetService())
+	}
+
+	return &grpc_health_v1.HealthCheckResponse{Status: s.servingStatus(ctx)}, nil
+}
+
+func (s *grpcHealthServer) Watch(req *grpc_health_v1.HealthCheckRequest, stream grpc_health_v1.Health_WatchServer) error {
+	if req.GetService() != "" {
+		// The service will never be known, as the protocol requires the call is not terminated.
+		if err := stream.Send(&grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVICE_UNKNOWN}); err != nil {
+			return err
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case <-s.done:
+			return status.Error(codes.Unavailable, "the exporter is shutting down")
+		}
+	}
+
+	ticker := time.NewTicker(healthWatchInterval)
+	defer ticker.Stop()
+
+	last := grpc_health_v1.HealthCheckResponse_UNKNOWN
+	for {
+		// Only the changes are sent.
+		if current := s.servingStatus(stream.Context()); current != last {
+			if err := stream.Send(&grpc_health_v1.HealthCheckResponse{Status: current}); err != nil {
+				return err
+			}
+			last = current
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case <-s.done:
+			return status.Error(codes.Unavailable, "the exporter is shutting down")
+		case <-ticker.C:
+		}
+	}
+}
+
+// servingStatus pings MongoDB like mongodb_up does.
+func (s *grpcHealthServer) servingStatus(ctx context.Context) grpc_health_v1.HealthCheckResponse_ServingStatus {
+	ctx, cancel := context.WithTimeout(ctx, s.e.opts.scrapeTimeout(nil))
+	defer cancel()
+
+	if err := s.e.ping(ctx); err != nil {
+		s.e.logger.Debugf("gRPC health check failed: %v", err)
+
+		return grpc_health_v1.HealthCheckResponse_NOT_SERVING
+	}
+
+	return grpc_health_v1.HealthCheckResponse_SERVING
+}
+
+var _ grpc_health_v1.HealthServer = (*grpcHealthServer)(nil)
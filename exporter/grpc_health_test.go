@@ -0,0 +1,76 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+func TestGRPCHealth(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	e := New(&Opts{
+		URI:                      "mongodb://127.0.0.1:12345",
+		Logger:                   logrus.New(),
+		ServerSelectionTimeoutMS: 100,
+		DisableDefaultRegistry:   true,
+		EnableGRPCHealth:         true,
+		GRPCHealthAddr:           "127.0.0.1:0",
+	})
+	require.NotNil(t, e.grpcListener)
+
+	conn, err := grpc.NewClient(e.grpcListener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close() //nolint:errcheck
+
+	client := grpc_health_v1.NewHealthClient(conn)
+
+	t.Run("Check", func(t *testing.T) {
+		res, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, res.GetStatus())
+
+		_, err = client.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: "unknown"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("Watch", func(t *testing.T) {
+		stream, err := client.Watch(ctx, &grpc_health_v1.HealthCheckRequest{})
+		require.NoError(t, err)
+
+		res, err := stream.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, res.GetStatus())
+
+		// The watchers are stopped on shutdown.
+		require.NoError(t, e.Shutdown(ctx))
+
+		_, err = stream.Recv()
+		assert.Error(t, err)
+	})
+}
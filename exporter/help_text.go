@@ -0,0 +1,99 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/model"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// helpTexts replaces the HELP text of the metrics by the ones of Opts.HelpTextOverrideFile.
+type helpTexts struct {
+	texts  map[string]string
+	logger *logrus.Logger
+	// checkOnce reports the texts not matching any metric of the first scrape MongoDB is up.
+	checkOnce sync.Once
+}
+
+// loadHelpTexts reads the YAML file mapping metric names to their help text. The names which
+// cannot be metric names are reported since they would never match.
+func loadHelpTexts(path string, logger *logrus.Logger) (*helpTexts, error) {
+	data, err := os.ReadFile(path) //nolint:gosec
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read the help text file")
+	}
+
+	var texts map[string]string
+	if err := yaml.Unmarshal(data, &texts); err != nil {
+		return nil, errors.Wrapf(err, "invalid help text file %s", path)
+	}
+
+	for _, name := range sortedKeys(texts) {
+		if !model.IsValidMetricName(model.LabelValue(name)) {
+			logger.Warnf("The help text of %q doesn't match any metric: it is not a valid metric name", name)
+		}
+	}
+
+	return &helpTexts{texts: texts, logger: logger}, nil
+}
+
+// gatherer returns a gatherer replacing the help of the metrics of g by the ones in the file.
+// The metrics not in the file keep their help.
+func (h *helpTexts) gatherer(g prometheus.Gatherer) prometheus.Gatherer {
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		mfs, err := g.Gather()
+
+		found := make(map[string]bool, len(h.texts))
+		for _, mf := range mfs {
+			if help, ok := h.texts[mf.GetName()]; ok {
+				help := help
+				mf.Help = &help
+				found[mf.GetName()] = true
+			}
+		}
+
+		// Most metrics are missing when MongoDB is down.
+		if !mongodbDown(mfs) {
+			h.checkOnce.Do(func() {
+				for _, name := range sortedKeys(h.texts) {
+					if !found[name] && model.IsValidMetricName(model.LabelValue(name)) {
+						h.logger.Warnf("The help text of %q doesn't match any metric of the first scrape", name)
+					}
+				}
+			})
+		}
+
+		return mfs, err
+	})
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,65 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHelpTextOverride(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "help.yml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+mongodb_up: "1 if the exporter can ping MongoDB, see https://wiki.example.com/mongodb_up."
+mongodb_not_exposed: "Never matches."
+invalid-name: "Not a metric name."
+`), 0o600))
+
+	logger, hook := test.NewNullLogger()
+
+	e := New(&Opts{
+		URI:                      "mongodb://127.0.0.1:12345",
+		Logger:                   logger,
+		ServerSelectionTimeoutMS: 100,
+		DisableDefaultRegistry:   true,
+		HelpTextOverrideFile:     path,
+	})
+
+	rr := httptest.NewRecorder()
+	e.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Contains(t, rr.Body.String(), "# HELP mongodb_up 1 if the exporter can ping MongoDB, see https://wiki.example.com/mongodb_up.\n")
+	// The metrics not in the file keep their help.
+	assert.Contains(t, rr.Body.String(), "# HELP collector_scrape_time_ms Time taken for scrape by collector\n")
+
+	var warnings []string
+	for _, entry := range hook.AllEntries() {
+		if entry.Level == logrus.WarnLevel {
+			warnings = append(warnings, entry.Message)
+		}
+	}
+	assert.Equal(t, []string{`The help text of "invalid-name" doesn't match any metric: it is not a valid metric name`}, warnings)
+
+	_, err := loadHelpTexts(filepath.Join(t.TempDir(), "missing.yml"), logger)
+	assert.Error(t, err)
+}
@@ -67,7 +67,7 @@ func (d *indexstatsCollector) collect(ch chan<- prometheus.Metric) {
 
 	var collections []string
 	if d.discoveringMode {
-		onlyCollectionsNamespaces, err := listAllCollections(d.ctx, client, d.collections, systemDBs, true)
+		onlyCollectionsNamespaces, err := cachedListAllCollections(d.ctx, client, d.collections, systemDBs, true)
 		if err != nil {
 			logger.Errorf("cannot auto discover databases and collections: %s", err.Error())
 
@@ -102,6 +102,11 @@ func (d *indexstatsCollector) collect(ch chan<- prometheus.Metric) {
 		if err != nil {
 			logger.Errorf("cannot get $indexStats cursor for collection %s.%s: %s", database, collection, err)
 
+			// The collection was dropped, don't keep it in the discovery results.
+			if isNamespaceNotFound(err) {
+				invalidateDiscoveryCache(d.ctx)
+			}
+
 			continue
 		}
 
@@ -109,6 +114,10 @@ func (d *indexstatsCollector) collect(ch chan<- prometheus.Metric) {
 		if err = cursor.All(d.ctx, &stats); err != nil {
 			logger.Errorf("cannot get $indexStats for collection %s.%s: %s", database, collection, err)
 
+			if isNamespaceNotFound(err) {
+				invalidateDiscoveryCache(d.ctx)
+			}
+
 			continue
 		}
 
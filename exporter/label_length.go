@@ -0,0 +1,85 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"fmt"
+	"hash/fnv"
+	"unicode/utf8"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// labelLengthGatherer truncates the label values longer than maxLength of the metrics returned
+// by the wrapped gatherer. Prometheus setups having a label length limit reject the whole scrape
+// if a single value, like a very long collection name, is over the limit.
+type labelLengthGatherer struct {
+	gatherer  prometheus.Gatherer
+	maxLength int
+}
+
+// limitLabelValues returns a gatherer truncating the label values longer than maxLength.
+// If maxLength is zero or negative, the gatherer is returned unchanged.
+func limitLabelValues(g prometheus.Gatherer, maxLength int) prometheus.Gatherer {
+	if maxLength <= 0 {
+		return g
+	}
+
+	return &labelLengthGatherer{gatherer: g, maxLength: maxLength}
+}
+
+func (g *labelLengthGatherer) Gather() ([]*dto.MetricFamily, error) {
+	mfs, err := g.gatherer.Gather()
+
+	for _, mf := range mfs {
+		for _, m := range mf.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if len(l.GetValue()) > g.maxLength {
+					v := truncateLabelValue(l.GetValue(), g.maxLength)
+					l.Value = &v
+				}
+			}
+		}
+	}
+
+	return mfs, err
+}
+
+// truncateLabelValue cuts v to maxLength bytes. The end of the value is replaced by a hash of the
+// whole value, otherwise long names sharing the same prefix, like db.collection_2023_01 and
+// db.collection_2023_02, would end up being the same series. Two values could still have the
+// same prefix and hash but, with a 32 bits hash, it is unlikely for the few values sharing a
+// prefix.
+func truncateLabelValue(v string, maxLength int) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(v))
+	suffix := fmt.Sprintf("~%08x", h.Sum32())
+
+	if maxLength <= len(suffix) {
+		return suffix[len(suffix)-maxLength:]
+	}
+
+	n := maxLength - len(suffix)
+	// Don't split multi byte characters.
+	for n > 0 && !utf8.RuneStart(v[n]) {
+		n--
+	}
+
+	return v[:n] + suffix
+}
+
+var _ prometheus.Gatherer = (*labelLengthGatherer)(nil)
@@ -0,0 +1,75 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitLabelValues(t *testing.T) {
+	longName := "collection_" + strings.Repeat("x", 300)
+	desc := prometheus.NewDesc("mongodb_collstats_storageStats_count", "Number of documents.",
+		[]string{"database", "collection"}, nil)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(metricsCollector{
+		prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 1, "db", longName+"_1"),
+		prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 2, "db", longName+"_2"),
+		prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 3, "db", "short"),
+	})
+
+	// No limit keeps the values unchanged.
+	assert.Equal(t, registry, limitLabelValues(registry, 0))
+
+	mfs, err := limitLabelValues(registry, 64).Gather()
+	require.NoError(t, err)
+	require.Len(t, mfs, 1)
+
+	values := make(map[string]bool)
+	for _, m := range mfs[0].GetMetric() {
+		for _, l := range m.GetLabel() {
+			assert.LessOrEqual(t, len(l.GetValue()), 64)
+			if l.GetName() == "collection" {
+				values[l.GetValue()] = true
+			}
+		}
+	}
+
+	// The hash suffix keeps values sharing the same prefix unique.
+	assert.Len(t, values, 3)
+	assert.True(t, values["short"])
+}
+
+func TestTruncateLabelValue(t *testing.T) {
+	v := truncateLabelValue(strings.Repeat("a", 100), 20)
+	assert.Len(t, v, 20)
+	assert.True(t, strings.HasPrefix(v, "aaaaaaaaaaa~"))
+
+	// It is stable between scrapes.
+	assert.Equal(t, v, truncateLabelValue(strings.Repeat("a", 100), 20))
+
+	// Multi byte characters are not split.
+	v = truncateLabelValue(strings.Repeat("é", 50), 20)
+	assert.LessOrEqual(t, len(v), 20)
+	assert.True(t, strings.HasPrefix(v, "ééééé~"))
+
+	assert.Len(t, truncateLabelValue(strings.Repeat("a", 100), 4), 4)
+}
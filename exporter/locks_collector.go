@@ -0,0 +1,124 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type locksCollector struct {
+	ctx  context.Context
+	base *baseCollector
+}
+
+// newLocksCollector creates a collector for the lock acquisitions per lock type and mode
+// reported by serverStatus.
+func newLocksCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger) *locksCollector {
+	return &locksCollector{
+		ctx:  ctx,
+		base: newBaseCollector(client, logger),
+	}
+}
+
+func (d *locksCollector) Describe(ch chan<- *prometheus.Desc) {
+	d.base.Describe(d.ctx, ch, d.collect)
+}
+
+func (d *locksCollector) Collect(ch chan<- prometheus.Metric) {
+	d.base.Collect(ch)
+}
+
+func (d *locksCollector) collect(ch chan<- prometheus.Metric) {
+	defer measureCollectTime(ch, "mongodb", "locks")()
+
+	logger := d.base.logger
+
+	m, err := serverStatus(d.ctx, d.base.client)
+	if err != nil {
+		logger.Errorf("cannot get lock stats: %s", err)
+
+		return
+	}
+
+	for _, metric := range lockAcquisitionMetrics(m) {
+		ch <- metric
+	}
+}
+
+// lockAcquisitionMetrics returns the acquisition counters of the locks section of a serverStatus document.
+// It has a document per lock type, like Global, Database or Collection, with the counters
+// per lock mode: r and w for the intent locks, R and W for the shared and exclusive locks.
+func lockAcquisitionMetrics(m bson.M) []prometheus.Metric {
+	locks, ok := m["locks"].(bson.M)
+	if !ok {
+		return nil
+	}
+
+	counters := []struct {
+		field string
+		desc  *prometheus.Desc
+	}{
+		{
+			field: "acquireCount",
+			desc: prometheus.NewDesc("mongodb_locks_acquire_total",
+				"Number of times the lock was acquired in the mode.", []string{"type", "mode"}, nil),
+		},
+		{
+			field: "acquireWaitCount",
+			desc: prometheus.NewDesc("mongodb_locks_acquire_wait_total",
+				"Number of times the lock acquisition had to wait because the lock was held in a conflicting mode.",
+				[]string{"type", "mode"}, nil),
+		},
+		{
+			field: "timeAcquiringMicros",
+			desc: prometheus.NewDesc("mongodb_locks_time_acquiring_micros_total",
+				"Cumulative wait time in microseconds for the lock acquisitions.", []string{"type", "mode"}, nil),
+		},
+	}
+
+	var metrics []prometheus.Metric
+
+	for lockType, v := range locks {
+		lock, ok := v.(bson.M)
+		if !ok {
+			continue
+		}
+
+		for _, c := range counters {
+			modes, ok := lock[c.field].(bson.M)
+			if !ok {
+				continue
+			}
+
+			for mode, value := range modes {
+				f, err := asFloat64(value)
+				if err != nil || f == nil {
+					continue
+				}
+				metrics = append(metrics, prometheus.MustNewConstMetric(c.desc, prometheus.CounterValue, *f, lockType, mode))
+			}
+		}
+	}
+
+	return metrics
+}
+
+var _ prometheus.Collector = (*locksCollector)(nil)
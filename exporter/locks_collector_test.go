@@ -0,0 +1,83 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestLocksMetrics(t *testing.T) {
+	m := bson.M{
+		"locks": bson.M{
+			"Global": bson.M{
+				"acquireCount": bson.M{
+					"r": int64(2340),
+					"w": int64(120),
+					"W": int64(4),
+				},
+				"acquireWaitCount": bson.M{
+					"W": int64(1),
+				},
+				"timeAcquiringMicros": bson.M{
+					"W": int64(350),
+				},
+			},
+			"Database": bson.M{
+				"acquireCount": bson.M{
+					"r": int64(900),
+					"W": int64(2),
+				},
+			},
+			"Collection": bson.M{
+				"acquireCount": bson.M{
+					"r": int64(850),
+				},
+			},
+			"Mutex": bson.M{
+				"acquireCount": bson.M{
+					"r": int32(10),
+				},
+			},
+		},
+	}
+
+	expected := `
+	# HELP mongodb_locks_acquire_total Number of times the lock was acquired in the mode.
+	# TYPE mongodb_locks_acquire_total counter
+	mongodb_locks_acquire_total{mode="W",type="Database"} 2
+	mongodb_locks_acquire_total{mode="W",type="Global"} 4
+	mongodb_locks_acquire_total{mode="r",type="Collection"} 850
+	mongodb_locks_acquire_total{mode="r",type="Database"} 900
+	mongodb_locks_acquire_total{mode="r",type="Global"} 2340
+	mongodb_locks_acquire_total{mode="r",type="Mutex"} 10
+	mongodb_locks_acquire_total{mode="w",type="Global"} 120
+	# HELP mongodb_locks_acquire_wait_total Number of times the lock acquisition had to wait because the lock was held in a conflicting mode.
+	# TYPE mongodb_locks_acquire_wait_total counter
+	mongodb_locks_acquire_wait_total{mode="W",type="Global"} 1
+	# HELP mongodb_locks_time_acquiring_micros_total Cumulative wait time in microseconds for the lock acquisitions.
+	# TYPE mongodb_locks_time_acquiring_micros_total counter
+	mongodb_locks_time_acquiring_micros_total{mode="W",type="Global"} 350` + "\n"
+
+	err := testutil.CollectAndCompare(metricsCollector(lockAcquisitionMetrics(m)), strings.NewReader(expected))
+	assert.NoError(t, err)
+
+	assert.Empty(t, lockAcquisitionMetrics(bson.M{"ok": float64(1)}))
+}
@@ -0,0 +1,182 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// fanOutConcurrency is the maximum number of members queried at the same time.
+	fanOutConcurrency = 4
+	// fanOutMemberTimeout bounds the time spent connecting to a member and running serverStatus.
+	fanOutMemberTimeout = 5 * time.Second
+	// fanOutTimeout bounds the whole fan-out if the scrape context has no deadline.
+	fanOutTimeout = defaultScrapeTimeoutSeconds * time.Second
+)
+
+// memberSections are the node-local sections of serverStatus exposed for every member.
+var memberSections = []string{"connections", "mem", "network", "opcounters", "wiredTiger.cache"} //nolint:gochecknoglobals
+
+// memberStatusFunc returns the serverStatus of the replica set member at host.
+type memberStatusFunc func(ctx context.Context, host string) (bson.M, error)
+
+type membersCollector struct {
+	ctx          context.Context
+	base         *baseCollector
+	memberStatus memberStatusFunc
+}
+
+// newMembersCollector creates a collector for the serverStatus of every member of the replica set
+// of the connected node. memberStatus is called for every member found by replSetGetStatus.
+func newMembersCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger, memberStatus memberStatusFunc) *membersCollector {
+	return &membersCollector{
+		ctx:          ctx,
+		base:         newBaseCollector(client, logger),
+		memberStatus: memberStatus,
+	}
+}
+
+func (d *membersCollector) Describe(ch chan<- *prometheus.Desc) {
+	d.base.Describe(d.ctx, ch, d.collect)
+}
+
+func (d *membersCollector) Collect(ch chan<- prometheus.Metric) {
+	d.base.Collect(ch)
+}
+
+func (d *membersCollector) collect(ch chan<- prometheus.Metric) {
+	defer measureCollectTime(ch, "mongodb", "members")()
+
+	logger := d.base.logger
+
+	var m bson.M
+	cmd := bson.D{{Key: "replSetGetStatus", Value: "1"}}
+	if err := d.base.client.Database("admin").RunCommand(d.ctx, cmd).Decode(&m); err != nil {
+		if e, ok := err.(mongo.CommandError); ok { //nolint:errorlint
+			if e.Code == replicationNotYetInitialized || e.Code == replicationNotEnabled {
+				return
+			}
+		}
+		logger.Errorf("cannot get the replica set members: %s", err)
+
+		return
+	}
+
+	for _, metric := range fanOutMetrics(d.ctx, memberHosts(m), d.memberStatus, logger) {
+		ch <- metric
+	}
+}
+
+// memberHosts returns the host of every member of a replSetGetStatus response.
+func memberHosts(status bson.M) []string {
+	members, ok := status["members"].(primitive.A)
+	if !ok {
+		return nil
+	}
+
+	hosts := make([]string, 0, len(members))
+	for _, member := range members {
+		member, ok := member.(bson.M)
+		if !ok {
+			continue
+		}
+
+		if name, ok := member["name"].(string); ok && name != "" {
+			hosts = append(hosts, name)
+		}
+	}
+
+	return hosts
+}
+
+// fanOutMetrics gets the serverStatus of the members, at most fanOutConcurrency at a time, and returns
+// mongodb_member_up and the metrics of memberSections labelled with member_host. An unreachable member
+// is only reported by mongodb_member_up so it doesn't fail the scrape.
+// The whole fan-out ends by the deadline of ctx, or after fanOutTimeout if it has none, so the members
+// waiting for a slot don't delay the scrape once it is over.
+func fanOutMetrics(ctx context.Context, hosts []string, memberStatus memberStatusFunc, logger *logrus.Logger) []prometheus.Metric {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, fanOutTimeout)
+		defer cancel()
+	}
+
+	results := make([][]prometheus.Metric, len(hosts))
+	sem := make(chan struct{}, fanOutConcurrency)
+	up := prometheus.NewDesc("mongodb_member_up",
+		"Whether the replica set member could be queried.", []string{"member_host"}, nil)
+
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		wg.Add(1)
+
+		go func(i int, host string) {
+			defer wg.Done()
+
+			down := func(err error) {
+				logger.Warnf("cannot get the serverStatus of the member %s: %s", host, err)
+				results[i] = []prometheus.Metric{prometheus.MustNewConstMetric(up, prometheus.GaugeValue, 0, host)}
+			}
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				down(ctx.Err())
+
+				return
+			}
+
+			memberCtx, cancel := context.WithTimeout(ctx, fanOutMemberTimeout)
+			defer cancel()
+
+			ss, err := memberStatus(memberCtx, host)
+			if err != nil {
+				down(err)
+
+				return
+			}
+
+			metrics := []prometheus.Metric{prometheus.MustNewConstMetric(up, prometheus.GaugeValue, 1, host)}
+			sections := bson.M{}
+			for _, path := range memberSections {
+				copyPath(sections, ss, strings.Split(path, "."))
+			}
+			results[i] = append(metrics, makeMetrics("member_ss", sections, map[string]string{"member_host": host}, false)...)
+		}(i, host)
+	}
+
+	wg.Wait()
+
+	var metrics []prometheus.Metric
+	for _, r := range results {
+		metrics = append(metrics, r...)
+	}
+
+	return metrics
+}
+
+var _ prometheus.Collector = (*membersCollector)(nil)
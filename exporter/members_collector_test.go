@@ -0,0 +1,107 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestFanOutMetrics(t *testing.T) {
+	status := bson.M{
+		"set": "rs0",
+		"members": primitive.A{
+			bson.M{"_id": int32(0), "name": "rs0-1:27017", "stateStr": "PRIMARY", "self": true},
+			bson.M{"_id": int32(1), "name": "rs0-2:27017", "stateStr": "(not reachable/healthy)"},
+		},
+	}
+	hosts := memberHosts(status)
+	assert.Equal(t, []string{"rs0-1:27017", "rs0-2:27017"}, hosts)
+
+	memberStatus := func(ctx context.Context, host string) (bson.M, error) {
+		if host != "rs0-1:27017" {
+			return nil, errors.New("server selection timeout")
+		}
+
+		return bson.M{
+			"host":        "rs0-1",
+			"connections": bson.M{"current": int32(12), "available": int32(838848)},
+			// Sections not in memberSections are not exposed.
+			"asserts": bson.M{"regular": int32(0)},
+		}, nil
+	}
+
+	expected := `
+	# HELP mongodb_member_ss_connections_available member_ss.connections.
+	# TYPE mongodb_member_ss_connections_available untyped
+	mongodb_member_ss_connections_available{member_host="rs0-1:27017"} 838848
+	# HELP mongodb_member_ss_connections_current member_ss.connections.
+	# TYPE mongodb_member_ss_connections_current untyped
+	mongodb_member_ss_connections_current{member_host="rs0-1:27017"} 12
+	# HELP mongodb_member_up Whether the replica set member could be queried.
+	# TYPE mongodb_member_up gauge
+	mongodb_member_up{member_host="rs0-1:27017"} 1
+	mongodb_member_up{member_host="rs0-2:27017"} 0` + "\n"
+
+	metrics := fanOutMetrics(context.Background(), hosts, memberStatus, logrus.New())
+	err := testutil.CollectAndCompare(metricsCollector(metrics), strings.NewReader(expected))
+	assert.NoError(t, err)
+}
+
+func TestFanOutMetricsDeadline(t *testing.T) {
+	hosts := make([]string, 3*fanOutConcurrency)
+	for i := range hosts {
+		hosts[i] = fmt.Sprintf("rs0-%d:27017", i)
+	}
+
+	var calls int32
+	memberStatus := func(ctx context.Context, host string) (bson.M, error) {
+		atomic.AddInt32(&calls, 1)
+		<-ctx.Done()
+
+		return nil, ctx.Err()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	metrics := fanOutMetrics(ctx, hosts, memberStatus, logrus.New())
+	assert.Less(t, time.Since(start), time.Second, "the fan-out must end by the deadline of the scrape")
+	assert.Len(t, metrics, len(hosts), "every member is reported as down")
+	assert.Equal(t, int32(fanOutConcurrency), atomic.LoadInt32(&calls), "the members waiting for a slot are not queried")
+}
+
+func TestMembersDisabledWithSRV(t *testing.T) {
+	opts := &Opts{FanOutToMembers: true, URI: "mongodb://127.0.0.1:27017"}
+	e := &Exporter{opts: opts, lock: &sync.Mutex{}}
+	assert.True(t, collectorEnabled(e.collectorStates(typeMongod, *opts), collectorMembers))
+
+	opts.URI = "mongodb+srv://cluster.example.com"
+	assert.False(t, collectorEnabled(e.collectorStates(typeMongod, *opts), collectorMembers))
+}
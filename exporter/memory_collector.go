@@ -0,0 +1,101 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// serverStatus.mem reports its values in megabytes.
+const bytesPerMB = 1024 * 1024
+
+type memoryCollector struct {
+	ctx  context.Context
+	base *baseCollector
+}
+
+// newMemoryCollector creates a collector for the memory usage reported by serverStatus.mem and serverStatus.tcmalloc.
+func newMemoryCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger) *memoryCollector {
+	return &memoryCollector{
+		ctx:  ctx,
+		base: newBaseCollector(client, logger),
+	}
+}
+
+func (d *memoryCollector) Describe(ch chan<- *prometheus.Desc) {
+	d.base.Describe(d.ctx, ch, d.collect)
+}
+
+func (d *memoryCollector) Collect(ch chan<- prometheus.Metric) {
+	d.base.Collect(ch)
+}
+
+func (d *memoryCollector) collect(ch chan<- prometheus.Metric) {
+	defer measureCollectTime(ch, "mongodb", "memory")()
+
+	logger := d.base.logger
+
+	m, err := serverStatus(d.ctx, d.base.client)
+	if err != nil {
+		logger.Errorf("cannot get memory stats: %s", err)
+
+		return
+	}
+
+	for _, metric := range memoryMetrics(m) {
+		ch <- metric
+	}
+}
+
+// memoryMetrics returns the memory metrics from a serverStatus document.
+// The tcmalloc metrics are only returned when the server is built with tcmalloc.
+func memoryMetrics(m bson.M) []prometheus.Metric {
+	var metrics []prometheus.Metric
+	appendGauge := func(section bson.M, field string, scale float64, name, help string) {
+		f, err := asFloat64(section[field])
+		if err != nil || f == nil {
+			return
+		}
+		d := prometheus.NewDesc(name, help, nil, nil)
+		metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.GaugeValue, *f*scale))
+	}
+
+	if mem, ok := m["mem"].(bson.M); ok {
+		appendGauge(mem, "resident", bytesPerMB, "mongodb_memory_resident_bytes",
+			"Amount of RAM currently used by the database process.")
+		appendGauge(mem, "virtual", bytesPerMB, "mongodb_memory_virtual_bytes",
+			"Amount of virtual memory used by the database process.")
+	}
+
+	if generic, ok := walkTo(m, []string{"tcmalloc", "generic"}).(bson.M); ok {
+		appendGauge(generic, "current_allocated_bytes", 1, "mongodb_tcmalloc_allocated_bytes",
+			"Number of bytes currently allocated by the application through tcmalloc.")
+	}
+
+	if tc, ok := walkTo(m, []string{"tcmalloc", "tcmalloc"}).(bson.M); ok {
+		appendGauge(tc, "central_cache_free_bytes", 1, "mongodb_tcmalloc_central_cache_free_bytes",
+			"Number of free bytes in the tcmalloc central cache.")
+	}
+
+	return metrics
+}
+
+var _ prometheus.Collector = (*memoryCollector)(nil)
@@ -0,0 +1,81 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestMemoryMetrics(t *testing.T) {
+	m := bson.M{
+		"mem": bson.M{
+			"bits":     int32(64),
+			"resident": int32(150),
+			"virtual":  int32(1536),
+		},
+		"tcmalloc": bson.M{
+			"generic": bson.M{
+				"current_allocated_bytes": int64(123456789),
+				"heap_size":               int64(234567890),
+			},
+			"tcmalloc": bson.M{
+				"central_cache_free_bytes": int64(4096),
+			},
+		},
+	}
+
+	expected := strings.NewReader(`
+	# HELP mongodb_memory_resident_bytes Amount of RAM currently used by the database process.
+	# TYPE mongodb_memory_resident_bytes gauge
+	mongodb_memory_resident_bytes 1.572864e+08
+	# HELP mongodb_memory_virtual_bytes Amount of virtual memory used by the database process.
+	# TYPE mongodb_memory_virtual_bytes gauge
+	mongodb_memory_virtual_bytes 1.610612736e+09
+	# HELP mongodb_tcmalloc_allocated_bytes Number of bytes currently allocated by the application through tcmalloc.
+	# TYPE mongodb_tcmalloc_allocated_bytes gauge
+	mongodb_tcmalloc_allocated_bytes 1.23456789e+08
+	# HELP mongodb_tcmalloc_central_cache_free_bytes Number of free bytes in the tcmalloc central cache.
+	# TYPE mongodb_tcmalloc_central_cache_free_bytes gauge
+	mongodb_tcmalloc_central_cache_free_bytes 4096` + "\n")
+
+	err := testutil.CollectAndCompare(metricsCollector(memoryMetrics(m)), expected)
+	assert.NoError(t, err)
+}
+
+func TestMemoryMetricsWithoutTcmalloc(t *testing.T) {
+	m := bson.M{
+		"mem": bson.M{
+			"resident": int32(1),
+			"virtual":  int32(2),
+		},
+	}
+
+	expected := strings.NewReader(`
+	# HELP mongodb_memory_resident_bytes Amount of RAM currently used by the database process.
+	# TYPE mongodb_memory_resident_bytes gauge
+	mongodb_memory_resident_bytes 1.048576e+06
+	# HELP mongodb_memory_virtual_bytes Amount of virtual memory used by the database process.
+	# TYPE mongodb_memory_virtual_bytes gauge
+	mongodb_memory_virtual_bytes 2.097152e+06` + "\n")
+
+	err := testutil.CollectAndCompare(metricsCollector(memoryMetrics(m)), expected)
+	assert.NoError(t, err)
+}
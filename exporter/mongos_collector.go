@@ -0,0 +1,114 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type mongosCollector struct {
+	ctx            context.Context
+	base           *baseCollector
+	configClient   *mongo.Client
+	staleThreshold time.Duration
+}
+
+// newMongosCollector creates a collector for the mongos routers registered in config.mongos.
+// If configClient is not nil, the config database is read from it instead of through client.
+// A mongos whose last ping is older than staleThreshold is reported as down.
+func newMongosCollector(ctx context.Context, client, configClient *mongo.Client, logger *logrus.Logger, staleThreshold time.Duration) *mongosCollector {
+	if configClient == nil {
+		configClient = client
+	}
+
+	if staleThreshold <= 0 {
+		staleThreshold = defaultMongosStaleThreshold
+	}
+
+	return &mongosCollector{
+		ctx:            ctx,
+		base:           newBaseCollector(client, logger),
+		configClient:   configClient,
+		staleThreshold: staleThreshold,
+	}
+}
+
+func (d *mongosCollector) Describe(ch chan<- *prometheus.Desc) {
+	d.base.Describe(d.ctx, ch, d.collect)
+}
+
+func (d *mongosCollector) Collect(ch chan<- prometheus.Metric) {
+	d.base.Collect(ch)
+}
+
+func (d *mongosCollector) collect(ch chan<- prometheus.Metric) {
+	defer measureCollectTime(ch, "mongodb", "mongos")()
+
+	logger := d.base.logger
+
+	cursor, err := d.configClient.Database("config").Collection("mongos").Find(d.ctx, bson.D{})
+	if err != nil {
+		logger.Errorf("cannot get the mongos routers: %s", err)
+
+		return
+	}
+
+	var routers []bson.M
+	if err := cursor.All(d.ctx, &routers); err != nil {
+		logger.Errorf("cannot decode the mongos routers: %s", err)
+
+		return
+	}
+
+	for _, metric := range mongosRouterMetrics(routers, time.Now(), d.staleThreshold) {
+		ch <- metric
+	}
+}
+
+// mongosRouterMetrics returns mongodb_mongos_up for every document of config.mongos. A mongos pings the
+// config servers every 30 seconds so it is reported as down if its last ping is older than
+// staleThreshold at now. No metrics are returned if the cluster is not sharded.
+func mongosRouterMetrics(routers []bson.M, now time.Time, staleThreshold time.Duration) []prometheus.Metric {
+	desc := prometheus.NewDesc("mongodb_mongos_up",
+		"Whether the mongos router pinged the config servers recently.", []string{"host"}, nil)
+
+	metrics := make([]prometheus.Metric, 0, len(routers))
+
+	for _, router := range routers {
+		host, ok := router["_id"].(string)
+		if !ok {
+			continue
+		}
+
+		var up float64
+		if ping, ok := router["ping"].(primitive.DateTime); ok && now.Sub(ping.Time()) <= staleThreshold {
+			up = 1
+		}
+
+		metrics = append(metrics, prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, up, host))
+	}
+
+	return metrics
+}
+
+var _ prometheus.Collector = (*mongosCollector)(nil)
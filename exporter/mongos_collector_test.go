@@ -0,0 +1,64 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMongosRouterMetrics(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	routers := []bson.M{
+		{
+			"_id":          "mongos-1:27017",
+			"ping":         primitive.NewDateTimeFromTime(now.Add(-10 * time.Second)),
+			"up":           int64(86400),
+			"waiting":      true,
+			"mongoVersion": "6.0.5",
+		},
+		{
+			"_id":          "mongos-2:27017",
+			"ping":         primitive.NewDateTimeFromTime(now.Add(-2 * time.Hour)),
+			"up":           int64(3600),
+			"waiting":      true,
+			"mongoVersion": "6.0.5",
+		},
+		{
+			// Without a ping, the mongos is not known to be alive.
+			"_id": "mongos-3:27017",
+		},
+	}
+
+	expected := `
+	# HELP mongodb_mongos_up Whether the mongos router pinged the config servers recently.
+	# TYPE mongodb_mongos_up gauge
+	mongodb_mongos_up{host="mongos-1:27017"} 1
+	mongodb_mongos_up{host="mongos-2:27017"} 0
+	mongodb_mongos_up{host="mongos-3:27017"} 0` + "\n"
+
+	err := testutil.CollectAndCompare(metricsCollector(mongosRouterMetrics(routers, now, time.Minute)), strings.NewReader(expected))
+	assert.NoError(t, err)
+
+	// config.mongos is empty if the cluster is not sharded.
+	assert.Empty(t, mongosRouterMetrics(nil, now, time.Minute))
+}
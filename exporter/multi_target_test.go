@@ -18,10 +18,15 @@ package exporter
 import (
 	"fmt"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
 
+	"github.com/prometheus/common/expfmt"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/percona/mongodb_exporter/internal/tu"
 )
@@ -70,3 +75,57 @@ func TestMultiTarget(t *testing.T) {
 		assert.HTTPBodyContains(t, multiTargetHandler(serverMap), "GET", fmt.Sprintf("?target=%s", opt.URI), nil, expected[sn])
 	}
 }
+
+func TestMultiTargetName(t *testing.T) {
+	uri := "mongodb://127.0.0.1:12345"
+	e := New(&Opts{
+		URI:                      uri,
+		Logger:                   logrus.New(),
+		ServerSelectionTimeoutMS: 100,
+	})
+	serverMap := buildServerMap([]*Exporter{e}, logrus.New())
+
+	testCases := []struct {
+		name     string
+		query    url.Values
+		expected string
+	}{
+		{
+			name:     "name param",
+			query:    url.Values{"target": {uri}, "name": {"prod-billing"}},
+			expected: "prod-billing",
+		},
+		{
+			name:     "host fallback",
+			query:    url.Values{"target": {uri}},
+			expected: "127.0.0.1:12345",
+		},
+		{
+			name:     "sanitized",
+			query:    url.Values{"target": {uri}, "name": {"prod billing/eu"}},
+			expected: "prod_billing_eu",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			multiTargetHandler(serverMap).ServeHTTP(rr, httptest.NewRequest("GET", "/scrape?"+tc.query.Encode(), nil))
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var parser expfmt.TextParser
+			families, err := parser.TextToMetricFamilies(rr.Body)
+			require.NoError(t, err)
+			require.Contains(t, families, "mongodb_up")
+
+			var names []string
+			for _, l := range families["mongodb_up"].GetMetric()[0].GetLabel() {
+				if l.GetName() == labelTargetName {
+					names = append(names, l.GetValue())
+				}
+			}
+			assert.Equal(t, []string{tc.expected}, names)
+		})
+	}
+}
@@ -0,0 +1,105 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+type collectorNameKey struct{}
+
+type permissionErrorsKey struct{}
+
+// permissionErrors records the collectors whose commands were rejected because the exporter
+// user lacks the privileges to run them during a scrape.
+type permissionErrors struct {
+	mu         sync.Mutex
+	collectors map[string]bool
+}
+
+// withPermissionErrors returns a context recording the permission errors of the scrape.
+func withPermissionErrors(ctx context.Context) context.Context {
+	return context.WithValue(ctx, permissionErrorsKey{}, &permissionErrors{collectors: make(map[string]bool)})
+}
+
+// withCollectorName returns a context for the commands run by the collector so the permission
+// errors can be attributed to it.
+func withCollectorName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, collectorNameKey{}, name)
+}
+
+// notePermissionError records a permission error for the collector running the command, if
+// the context is the one of a collector.
+func notePermissionError(ctx context.Context) {
+	pe, ok := ctx.Value(permissionErrorsKey{}).(*permissionErrors)
+	if !ok {
+		return
+	}
+
+	name, ok := ctx.Value(collectorNameKey{}).(string)
+	if !ok {
+		return
+	}
+
+	pe.mu.Lock()
+	pe.collectors[name] = true
+	pe.mu.Unlock()
+}
+
+// permissionErrorsMonitor is the driver command monitor noting the commands failing with an
+// authorization error. The failure only has the error message, which starts with the error
+// code name.
+var permissionErrorsMonitor = &event.CommandMonitor{ //nolint:gochecknoglobals
+	Failed: func(ctx context.Context, evt *event.CommandFailedEvent) {
+		if strings.HasPrefix(evt.Failure, "(Unauthorized)") {
+			notePermissionError(ctx)
+		}
+	},
+}
+
+// permissionErrorsMetrics exposes whether the commands of each enabled collector failed with an
+// authorization error in this scrape.
+func permissionErrorsMetrics(ctx context.Context, states []collectorState) *prometheus.GaugeVec {
+	gv := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "mongodb_exporter_collector_permission_error",
+		Help: "Whether a command of the collector failed because the exporter user is not authorized to run it.",
+	}, []string{"collector"})
+
+	pe, _ := ctx.Value(permissionErrorsKey{}).(*permissionErrors)
+
+	for _, state := range states {
+		if !state.enabled {
+			continue
+		}
+
+		var value float64
+		if pe != nil {
+			pe.mu.Lock()
+			if pe.collectors[state.name] {
+				value = 1
+			}
+			pe.mu.Unlock()
+		}
+		gv.WithLabelValues(state.name).Set(value)
+	}
+
+	return gv
+}
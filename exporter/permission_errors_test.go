@@ -0,0 +1,95 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestPermissionErrorsMetrics(t *testing.T) {
+	ctx := withPermissionErrors(context.Background())
+	states := []collectorState{
+		{name: collectorTopMetrics, enabled: true},
+		{name: collectorDBStats, enabled: true},
+		{name: collectorShards, enabled: false},
+	}
+
+	// The top command is rejected for the top collector.
+	permissionErrorsMonitor.Failed(withCollectorName(ctx, collectorTopMetrics), &event.CommandFailedEvent{
+		CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "top", DatabaseName: "admin"},
+		Failure:              "(Unauthorized) not authorized on admin to execute command { top: 1 }",
+	})
+	// Other failures are not permission errors.
+	permissionErrorsMonitor.Failed(withCollectorName(ctx, collectorDBStats), &event.CommandFailedEvent{
+		CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "dbStats", DatabaseName: "test"},
+		Failure:              "(NamespaceNotFound) ns not found",
+	})
+	// Commands run outside of a collector are ignored.
+	permissionErrorsMonitor.Failed(ctx, &event.CommandFailedEvent{Failure: "(Unauthorized) not authorized"})
+
+	expected := `
+	# HELP mongodb_exporter_collector_permission_error Whether a command of the collector failed because the exporter user is not authorized to run it.
+	# TYPE mongodb_exporter_collector_permission_error gauge
+	mongodb_exporter_collector_permission_error{collector="dbstats"} 0
+	mongodb_exporter_collector_permission_error{collector="topmetrics"} 1` + "\n"
+
+	err := testutil.CollectAndCompare(permissionErrorsMetrics(ctx, states), strings.NewReader(expected))
+	assert.NoError(t, err)
+
+	// Every scrape starts without errors so the metric is back to 0 once the collector succeeds.
+	expected = `
+	# HELP mongodb_exporter_collector_permission_error Whether a command of the collector failed because the exporter user is not authorized to run it.
+	# TYPE mongodb_exporter_collector_permission_error gauge
+	mongodb_exporter_collector_permission_error{collector="dbstats"} 0
+	mongodb_exporter_collector_permission_error{collector="topmetrics"} 0` + "\n"
+
+	err = testutil.CollectAndCompare(permissionErrorsMetrics(withPermissionErrors(context.Background()), states),
+		strings.NewReader(expected))
+	assert.NoError(t, err)
+}
+
+func TestPermissionErrorsServerStatusCache(t *testing.T) {
+	ctx := withPermissionErrors(withServerStatusCache(context.Background()))
+
+	// Simulate a serverStatus rejected when it ran for the first collector.
+	cache := ctx.Value(serverStatusCacheKey{}).(*serverStatusCache) //nolint:forcetypeassert
+	cache.once.Do(func() {
+		cache.err = mongo.CommandError{Code: unauthorized, Name: "Unauthorized", Message: "not authorized on admin"}
+	})
+
+	_, err := serverStatus(withCollectorName(ctx, collectorAsserts), nil)
+	assert.Error(t, err)
+
+	states := []collectorState{
+		{name: collectorAsserts, enabled: true},
+		{name: collectorGlobalLock, enabled: true},
+	}
+	expected := `
+	# HELP mongodb_exporter_collector_permission_error Whether a command of the collector failed because the exporter user is not authorized to run it.
+	# TYPE mongodb_exporter_collector_permission_error gauge
+	mongodb_exporter_collector_permission_error{collector="asserts"} 1
+	mongodb_exporter_collector_permission_error{collector="globallock"} 0` + "\n"
+
+	err = testutil.CollectAndCompare(permissionErrorsMetrics(ctx, states), strings.NewReader(expected))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,75 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// defaultPushJob is the job name of the metrics pushed to the Pushgateway if none is given.
+const defaultPushJob = "mongodb_exporter"
+
+// PushOnce collects the metrics once, with all the enabled collectors, and pushes them to the
+// Pushgateway at pushgatewayURL, or Opts.PushGatewayURL if it is empty, replacing the metrics of the
+// job and the Opts.PushGroupingLabels. An instance label with the MongoDB host is added if the
+// grouping labels don't have one so the exporters of several targets don't overwrite each other.
+func (e *Exporter) PushOnce(ctx context.Context, pushgatewayURL, jobName string) error {
+	if pushgatewayURL == "" {
+		pushgatewayURL = e.opts.PushGatewayURL
+	}
+	if pushgatewayURL == "" {
+		return errNoPushGateway
+	}
+	if jobName == "" {
+		jobName = defaultPushJob
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.opts.scrapeTimeout(nil))
+	defer cancel()
+
+	client, connectErr := e.getClient(ctx)
+	if connectErr != nil {
+		// mongodb_up is still pushed.
+		e.logger.Errorf("Cannot connect to MongoDB: %v", connectErr)
+	}
+	defer e.releaseClient(ctx, client)
+
+	var ti *topologyInfo
+	if client != nil {
+		ti = newTopologyInfo(ctx, client, e.logger)
+	}
+
+	registry := e.wrapGatherer(ctx, e.makeRegistry(ctx, client, ti, *e.opts), connectErr)
+	pusher := push.New(pushgatewayURL, jobName).Gatherer(registry)
+
+	if _, ok := e.opts.PushGroupingLabels["instance"]; !ok {
+		if host := hostFromURI(e.opts.URI); host != "" {
+			pusher = pusher.Grouping("instance", host)
+		}
+	}
+	for name, value := range e.opts.PushGroupingLabels {
+		pusher = pusher.Grouping(name, value)
+	}
+
+	if err := pusher.Push(); err != nil {
+		return fmt.Errorf("cannot push the metrics of %s to %s: %w", hostFromURI(e.opts.URI), redactURI(pushgatewayURL), err)
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPushOnce(t *testing.T) {
+	var method, path string
+	var body []byte
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	e := New(&Opts{
+		URI:                      "mongodb://127.0.0.1:12345",
+		Logger:                   logrus.New(),
+		ServerSelectionTimeoutMS: 100,
+		DisableDefaultRegistry:   true,
+		PushGatewayURL:           ts.URL,
+		PushGroupingLabels:       map[string]string{"env": "test"},
+	})
+	defer e.Shutdown(context.Background()) //nolint:errcheck
+
+	require.NoError(t, e.PushOnce(context.Background(), "", "backup_job"))
+
+	assert.Equal(t, http.MethodPut, method)
+	assert.Contains(t, path, "/metrics/job/backup_job/")
+	assert.Contains(t, path, "/env/test")
+	// The MongoDB host is the default instance.
+	assert.Contains(t, path, "/instance/127.0.0.1:12345")
+	assert.True(t, bytes.Contains(body, []byte("mongodb_up")), "mongodb_up is pushed")
+	assert.True(t, bytes.Contains(body, []byte("collector_scrape_time_ms")), "the general collector is pushed")
+	// The metrics are processed like the scraped ones.
+	assert.True(t, bytes.Contains(body, []byte("mongodb_exporter_scrape_errors_total")), "the scrape metrics are pushed")
+
+	e.opts.PushGatewayURL = ""
+	assert.ErrorIs(t, e.PushOnce(context.Background(), "", ""), errNoPushGateway)
+}
+
+func TestPushOnceError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	e := New(&Opts{
+		URI:                      "mongodb://127.0.0.1:12345",
+		Logger:                   logrus.New(),
+		ServerSelectionTimeoutMS: 100,
+		DisableDefaultRegistry:   true,
+		PushGatewayURL:           ts.URL,
+	})
+	defer e.Shutdown(context.Background()) //nolint:errcheck
+
+	err := e.PushOnce(context.Background(), "", "")
+	require.Error(t, err)
+	// With several targets, the error tells which one failed.
+	assert.Contains(t, err.Error(), "127.0.0.1:12345")
+}
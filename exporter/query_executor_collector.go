@@ -0,0 +1,107 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type queryExecutorCollector struct {
+	ctx  context.Context
+	base *baseCollector
+}
+
+// newQueryExecutorCollector creates a collector for the documents and index keys scanned by the
+// queries compared to the documents they return.
+func newQueryExecutorCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger) *queryExecutorCollector {
+	return &queryExecutorCollector{
+		ctx:  ctx,
+		base: newBaseCollector(client, logger),
+	}
+}
+
+func (d *queryExecutorCollector) Describe(ch chan<- *prometheus.Desc) {
+	d.base.Describe(d.ctx, ch, d.collect)
+}
+
+func (d *queryExecutorCollector) Collect(ch chan<- prometheus.Metric) {
+	d.base.Collect(ch)
+}
+
+func (d *queryExecutorCollector) collect(ch chan<- prometheus.Metric) {
+	defer measureCollectTime(ch, "mongodb", "queryexecutor")()
+
+	logger := d.base.logger
+
+	m, err := serverStatus(d.ctx, d.base.client)
+	if err != nil {
+		logger.Errorf("cannot get query executor stats: %s", err)
+
+		return
+	}
+
+	for _, metric := range queryExecutorMetrics(m) {
+		ch <- metric
+	}
+}
+
+// queryExecutorMetrics returns the scanned index keys, the scanned documents and the returned
+// documents counters from a serverStatus document. A high ratio of scanned to returned documents
+// usually means a missing index.
+func queryExecutorMetrics(m bson.M) []prometheus.Metric {
+	counters := []struct {
+		path []string
+		name string
+		help string
+	}{
+		{
+			path: []string{"metrics", "queryExecutor", "scanned"},
+			name: "mongodb_metrics_query_executor_scanned_total",
+			help: "Number of index items scanned during queries and query-plan evaluation.",
+		},
+		{
+			path: []string{"metrics", "queryExecutor", "scannedObjects"},
+			name: "mongodb_metrics_query_executor_scanned_objects_total",
+			help: "Number of documents scanned during queries and query-plan evaluation.",
+		},
+		{
+			path: []string{"metrics", "document", "returned"},
+			name: "mongodb_metrics_document_returned_total",
+			help: "Number of documents returned by queries.",
+		},
+	}
+
+	var metrics []prometheus.Metric
+
+	for _, c := range counters {
+		f, err := asFloat64(walkTo(m, c.path))
+		if err != nil || f == nil {
+			continue
+		}
+
+		d := prometheus.NewDesc(c.name, c.help, nil, nil)
+		metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.CounterValue, *f))
+	}
+
+	return metrics
+}
+
+var _ prometheus.Collector = (*queryExecutorCollector)(nil)
@@ -0,0 +1,62 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestQueryExecutorMetrics(t *testing.T) {
+	m := bson.M{
+		"metrics": bson.M{
+			"queryExecutor": bson.M{
+				"scanned":        int64(1200),
+				"scannedObjects": int64(5400),
+				"collectionScans": bson.M{
+					"nonTailable": int64(3),
+					"total":       int64(4),
+				},
+			},
+			"document": bson.M{
+				"deleted":  int64(1),
+				"inserted": int64(20),
+				"returned": int64(300),
+				"updated":  int64(2),
+			},
+		},
+	}
+
+	expected := `
+	# HELP mongodb_metrics_document_returned_total Number of documents returned by queries.
+	# TYPE mongodb_metrics_document_returned_total counter
+	mongodb_metrics_document_returned_total 300
+	# HELP mongodb_metrics_query_executor_scanned_objects_total Number of documents scanned during queries and query-plan evaluation.
+	# TYPE mongodb_metrics_query_executor_scanned_objects_total counter
+	mongodb_metrics_query_executor_scanned_objects_total 5400
+	# HELP mongodb_metrics_query_executor_scanned_total Number of index items scanned during queries and query-plan evaluation.
+	# TYPE mongodb_metrics_query_executor_scanned_total counter
+	mongodb_metrics_query_executor_scanned_total 1200` + "\n"
+
+	err := testutil.CollectAndCompare(metricsCollector(queryExecutorMetrics(m)), strings.NewReader(expected))
+	assert.NoError(t, err)
+
+	assert.Empty(t, queryExecutorMetrics(bson.M{"ok": float64(1)}))
+}
@@ -0,0 +1,143 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type replMetricsCollector struct {
+	ctx  context.Context
+	base *baseCollector
+}
+
+// newReplMetricsCollector creates a collector for the apply pipeline of the secondaries
+// reported by serverStatus.
+func newReplMetricsCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger) *replMetricsCollector {
+	return &replMetricsCollector{
+		ctx:  ctx,
+		base: newBaseCollector(client, logger),
+	}
+}
+
+func (d *replMetricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	d.base.Describe(d.ctx, ch, d.collect)
+}
+
+func (d *replMetricsCollector) Collect(ch chan<- prometheus.Metric) {
+	d.base.Collect(ch)
+}
+
+func (d *replMetricsCollector) collect(ch chan<- prometheus.Metric) {
+	defer measureCollectTime(ch, "mongodb", "replmetrics")()
+
+	logger := d.base.logger
+
+	m, err := serverStatus(d.ctx, d.base.client)
+	if err != nil {
+		logger.Errorf("cannot get repl metrics: %s", err)
+
+		return
+	}
+
+	for _, metric := range replApplyMetrics(m) {
+		ch <- metric
+	}
+
+	for _, metric := range replNetworkMetrics(m) {
+		ch <- metric
+	}
+}
+
+// replApplyMetrics returns the apply batches, the applied operations and the buffer of the
+// oplog entries fetched from the sync source from the metrics.repl section of a serverStatus
+// document. Only secondaries apply the oplog so no metrics are returned for the primary or
+// for nodes which are not replica set members.
+func replApplyMetrics(m bson.M) []prometheus.Metric {
+	if secondary, _ := walkTo(m, []string{"repl", "secondary"}).(bool); !secondary {
+		return nil
+	}
+
+	repl, ok := walkTo(m, []string{"metrics", "repl"}).(bson.M)
+	if !ok {
+		return nil
+	}
+
+	var metrics []prometheus.Metric
+	createMetric := func(name, help string, valueType prometheus.ValueType, path ...string) {
+		f, err := asFloat64(walkTo(repl, path))
+		if err != nil || f == nil {
+			return
+		}
+
+		d := prometheus.NewDesc(name, help, nil, nil)
+		metrics = append(metrics, prometheus.MustNewConstMetric(d, valueType, *f))
+	}
+
+	createMetric("mongodb_mongod_metrics_repl_apply_batches_total", "Number of oplog batches applied by the secondary.",
+		prometheus.CounterValue, "apply", "batches", "num")
+	createMetric("mongodb_mongod_metrics_repl_apply_ops_total", "Number of oplog operations applied by the secondary.",
+		prometheus.CounterValue, "apply", "ops")
+	createMetric("mongodb_mongod_metrics_repl_buffer_count", "Number of operations in the oplog buffer.",
+		prometheus.GaugeValue, "buffer", "count")
+	createMetric("mongodb_mongod_metrics_repl_buffer_size_bytes", "Size in bytes of the operations in the oplog buffer.",
+		prometheus.GaugeValue, "buffer", "sizeBytes")
+
+	return metrics
+}
+
+// replNetworkMetrics returns the getMore commands and the oplog entries fetched from the sync source,
+// during the initial sync and afterwards, from the metrics.repl.network section of a serverStatus
+// document. Like the apply metrics, they are only returned for secondaries.
+func replNetworkMetrics(m bson.M) []prometheus.Metric {
+	if secondary, _ := walkTo(m, []string{"repl", "secondary"}).(bool); !secondary {
+		return nil
+	}
+
+	network, ok := walkTo(m, []string{"metrics", "repl", "network"}).(bson.M)
+	if !ok {
+		return nil
+	}
+
+	var metrics []prometheus.Metric
+	createMetric := func(name, help string, scale float64, path ...string) {
+		f, err := asFloat64(walkTo(network, path))
+		if err != nil || f == nil {
+			return
+		}
+
+		d := prometheus.NewDesc(name, help, nil, nil)
+		metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.CounterValue, *f*scale))
+	}
+
+	createMetric("mongodb_mongod_metrics_repl_network_getmores_total",
+		"Number of getMore commands run by the secondary to fetch the oplog from the sync source.", 1, "getmores", "num")
+	createMetric("mongodb_mongod_metrics_repl_network_getmores_time_seconds_total",
+		"Time spent by the secondary in the getMore commands fetching the oplog.", 1e-3, "getmores", "totalMillis")
+	createMetric("mongodb_mongod_metrics_repl_network_bytes_total",
+		"Size in bytes of the oplog entries fetched from the sync source.", 1, "bytes")
+	createMetric("mongodb_mongod_metrics_repl_network_ops_total",
+		"Number of oplog entries fetched from the sync source.", 1, "ops")
+
+	return metrics
+}
+
+var _ prometheus.Collector = (*replMetricsCollector)(nil)
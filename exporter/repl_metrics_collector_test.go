@@ -0,0 +1,117 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestReplApplyMetrics(t *testing.T) {
+	replSection := func(secondary bool) bson.M {
+		return bson.M{
+			"setName":   "rs1",
+			"ismaster":  !secondary,
+			"secondary": secondary,
+		}
+	}
+	metricsRepl := bson.M{
+		"repl": bson.M{
+			"apply": bson.M{
+				"attemptsToBecomeSecondary": int64(1),
+				"batchSize":                 int64(5120),
+				"batches": bson.M{
+					"num":         int64(340),
+					"totalMillis": int64(95),
+				},
+				"ops": int64(5120),
+			},
+			"buffer": bson.M{
+				"count":        int64(12),
+				"maxSizeBytes": int64(268435456),
+				"sizeBytes":    int64(3072),
+			},
+		},
+	}
+
+	expected := `
+	# HELP mongodb_mongod_metrics_repl_apply_batches_total Number of oplog batches applied by the secondary.
+	# TYPE mongodb_mongod_metrics_repl_apply_batches_total counter
+	mongodb_mongod_metrics_repl_apply_batches_total 340
+	# HELP mongodb_mongod_metrics_repl_apply_ops_total Number of oplog operations applied by the secondary.
+	# TYPE mongodb_mongod_metrics_repl_apply_ops_total counter
+	mongodb_mongod_metrics_repl_apply_ops_total 5120
+	# HELP mongodb_mongod_metrics_repl_buffer_count Number of operations in the oplog buffer.
+	# TYPE mongodb_mongod_metrics_repl_buffer_count gauge
+	mongodb_mongod_metrics_repl_buffer_count 12
+	# HELP mongodb_mongod_metrics_repl_buffer_size_bytes Size in bytes of the operations in the oplog buffer.
+	# TYPE mongodb_mongod_metrics_repl_buffer_size_bytes gauge
+	mongodb_mongod_metrics_repl_buffer_size_bytes 3072` + "\n"
+
+	secondary := bson.M{"repl": replSection(true), "metrics": metricsRepl}
+	err := testutil.CollectAndCompare(metricsCollector(replApplyMetrics(secondary)), strings.NewReader(expected))
+	assert.NoError(t, err)
+
+	// The primary doesn't apply the oplog and standalone instances don't have the repl section.
+	assert.Empty(t, replApplyMetrics(bson.M{"repl": replSection(false), "metrics": metricsRepl}))
+	assert.Empty(t, replApplyMetrics(bson.M{"metrics": bson.M{"document": bson.M{"inserted": int64(1)}}}))
+}
+
+func TestReplNetworkMetrics(t *testing.T) {
+	repl := func(secondary bool) bson.M {
+		return bson.M{"setName": "rs1", "ismaster": !secondary, "secondary": secondary}
+	}
+	metricsRepl := bson.M{
+		"repl": bson.M{
+			"network": bson.M{
+				"bytes": int64(104857600),
+				"getmores": bson.M{
+					"num":             int64(2500),
+					"totalMillis":     int64(12500),
+					"numEmptyBatches": int64(3),
+				},
+				"notPrimaryLegacyUnacknowledgedWrites": int64(0),
+				"ops":                                  int64(150000),
+				"readersCreated":                       int64(2),
+			},
+		},
+	}
+
+	expected := `
+	# HELP mongodb_mongod_metrics_repl_network_bytes_total Size in bytes of the oplog entries fetched from the sync source.
+	# TYPE mongodb_mongod_metrics_repl_network_bytes_total counter
+	mongodb_mongod_metrics_repl_network_bytes_total 1.048576e+08
+	# HELP mongodb_mongod_metrics_repl_network_getmores_time_seconds_total Time spent by the secondary in the getMore commands fetching the oplog.
+	# TYPE mongodb_mongod_metrics_repl_network_getmores_time_seconds_total counter
+	mongodb_mongod_metrics_repl_network_getmores_time_seconds_total 12.5
+	# HELP mongodb_mongod_metrics_repl_network_getmores_total Number of getMore commands run by the secondary to fetch the oplog from the sync source.
+	# TYPE mongodb_mongod_metrics_repl_network_getmores_total counter
+	mongodb_mongod_metrics_repl_network_getmores_total 2500
+	# HELP mongodb_mongod_metrics_repl_network_ops_total Number of oplog entries fetched from the sync source.
+	# TYPE mongodb_mongod_metrics_repl_network_ops_total counter
+	mongodb_mongod_metrics_repl_network_ops_total 150000` + "\n"
+
+	secondary := bson.M{"repl": repl(true), "metrics": metricsRepl}
+	err := testutil.CollectAndCompare(metricsCollector(replNetworkMetrics(secondary)), strings.NewReader(expected))
+	assert.NoError(t, err)
+
+	assert.Empty(t, replNetworkMetrics(bson.M{"repl": repl(false), "metrics": metricsRepl}))
+	assert.Empty(t, replNetworkMetrics(bson.M{"repl": repl(true), "metrics": bson.M{"repl": bson.M{}}}))
+}
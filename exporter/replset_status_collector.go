@@ -21,7 +21,11 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/percona/mongodb_exporter/internal/proto"
+	"github.com/percona/mongodb_exporter/internal/util"
 )
 
 const (
@@ -35,6 +39,10 @@ type replSetGetStatusCollector struct {
 
 	compatibleMode bool
 	topologyInfo   labelsGetter
+
+	// diagnosticDataCompat is true if the diagnostic data collector runs in compatible mode. It
+	// already exposes some of the metrics of this collector with other labels.
+	diagnosticDataCompat bool
 }
 
 // newReplicationSetStatusCollector creates a collector for statistics on replication set.
@@ -84,6 +92,97 @@ func (d *replSetGetStatusCollector) collect(ch chan<- prometheus.Metric) {
 	for _, metric := range makeMetrics("", m, d.topologyInfo.baseLabels(), d.compatibleMode) {
 		ch <- metric
 	}
+
+	for _, metric := range replsetMemberMetrics(m, d.diagnosticDataCompat) {
+		ch <- metric
+	}
+
+	rs, err := util.ReplicasetConfig(d.ctx, client)
+	if err != nil {
+		logger.Errorf("cannot get replSetGetConfig: %s", err)
+
+		return
+	}
+
+	for _, metric := range replsetConfigMetrics(rs.Config, m, d.diagnosticDataCompat) {
+		ch <- metric
+	}
+}
+
+// replsetMemberMetrics returns the last heartbeat and the ping time of the members seen from
+// the current member. Its own entry doesn't have them so it is skipped.
+// If diagnosticDataCompat is true, the ping time is skipped since the diagnostic data collector
+// already exposes mongodb_mongod_replset_member_ping_ms with the state label.
+func replsetMemberMetrics(status bson.M, diagnosticDataCompat bool) []prometheus.Metric {
+	set, _ := status["set"].(string)
+	members, ok := status["members"].(primitive.A)
+	if !ok {
+		return nil
+	}
+
+	heartbeatDesc := prometheus.NewDesc("mongodb_mongod_replset_member_last_heartbeat_seconds",
+		"Unix timestamp of the last heartbeat received from the member.", []string{"set", "name"}, nil)
+	pingDesc := prometheus.NewDesc("mongodb_mongod_replset_member_ping_ms",
+		"Round trip time in milliseconds between the current member and the member.", []string{"set", "name"}, nil)
+
+	var metrics []prometheus.Metric
+	for _, member := range members {
+		member, ok := member.(bson.M)
+		if !ok {
+			continue
+		}
+
+		name, _ := member["name"].(string)
+
+		// Members never reached have the epoch as last heartbeat.
+		if hb, ok := member["lastHeartbeat"].(primitive.DateTime); ok && hb > 0 {
+			metrics = append(metrics, prometheus.MustNewConstMetric(heartbeatDesc, prometheus.GaugeValue,
+				float64(hb)/1000, set, name)) //nolint:gomnd
+		}
+
+		if ping, err := asFloat64(member["pingMs"]); err == nil && ping != nil && !diagnosticDataCompat {
+			metrics = append(metrics, prometheus.MustNewConstMetric(pingDesc, prometheus.GaugeValue, *ping, set, name))
+		}
+	}
+
+	return metrics
+}
+
+// replsetConfigMetrics returns the config version, the term and the number of members of the
+// replica set from the replSetGetConfig and replSetGetStatus responses.
+// If diagnosticDataCompat is true, mongodb_mongod_replset_number_of_members is already exposed by
+// the diagnostic data collector so it is skipped.
+func replsetConfigMetrics(cfg proto.RSConfig, status bson.M, diagnosticDataCompat bool) []prometheus.Metric {
+	var metrics []prometheus.Metric
+	labels := map[string]string{"set": cfg.ID}
+
+	createMetric := func(name, help string, value float64) {
+		const prefix = "mongodb_mongod_replset_"
+		d := prometheus.NewDesc(prefix+name, help, nil, labels)
+		metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.GaugeValue, value))
+	}
+
+	createMetric("config_version", "The version of the replica set configuration.", float64(cfg.Version))
+
+	// The term is only reported by replica sets using protocol version 1.
+	if term, err := asFloat64(status["term"]); err == nil && term != nil {
+		createMetric("term", "The election count of the replica set.", *term)
+	}
+
+	if !diagnosticDataCompat {
+		createMetric("number_of_members", "The number of replica set members.", float64(len(cfg.Members)))
+	}
+
+	var voting int
+	for _, member := range cfg.Members {
+		if member.Votes > 0 {
+			voting++
+		}
+	}
+	createMetric("number_of_voting_members", "The number of replica set members that can vote in elections.",
+		float64(voting))
+
+	return metrics
 }
 
 var _ prometheus.Collector = (*replSetGetStatusCollector)(nil)
@@ -21,10 +21,14 @@ import (
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 
+	"github.com/percona/mongodb_exporter/internal/proto"
 	"github.com/percona/mongodb_exporter/internal/tu"
 )
 
@@ -74,3 +78,98 @@ func TestReplsetStatusCollectorNoSharding(t *testing.T) {
 	metaMetricCount := 1
 	assert.Equal(t, metaMetricCount, count, "Mismatch in metric count for collector run on unsharded server")
 }
+
+func TestReplsetConfigMetrics(t *testing.T) {
+	cfg := proto.RSConfig{
+		ID:      "rs1",
+		Version: 5,
+		Members: []proto.Member{
+			{ID: 0, Host: "rs101:27017", Votes: 1},
+			{ID: 1, Host: "rs102:27017", Votes: 1},
+			{ID: 2, Host: "rs103:27017", Votes: 1, ArbiterOnly: true},
+			// Non-voting members, like the ones added for analytics, have priority 0 and no votes.
+			{ID: 3, Host: "rs104:27017", Votes: 0, Hidden: true},
+			{ID: 4, Host: "rs105:27017", Votes: 0},
+		},
+	}
+	status := bson.M{
+		"set":  "rs1",
+		"term": int64(3),
+	}
+
+	expected := strings.NewReader(`
+	# HELP mongodb_mongod_replset_config_version The version of the replica set configuration.
+	# TYPE mongodb_mongod_replset_config_version gauge
+	mongodb_mongod_replset_config_version{set="rs1"} 5
+	# HELP mongodb_mongod_replset_number_of_members The number of replica set members.
+	# TYPE mongodb_mongod_replset_number_of_members gauge
+	mongodb_mongod_replset_number_of_members{set="rs1"} 5
+	# HELP mongodb_mongod_replset_number_of_voting_members The number of replica set members that can vote in elections.
+	# TYPE mongodb_mongod_replset_number_of_voting_members gauge
+	mongodb_mongod_replset_number_of_voting_members{set="rs1"} 3
+	# HELP mongodb_mongod_replset_term The election count of the replica set.
+	# TYPE mongodb_mongod_replset_term gauge
+	mongodb_mongod_replset_term{set="rs1"} 3` + "\n")
+
+	err := testutil.CollectAndCompare(metricsCollector(replsetConfigMetrics(cfg, status, false)), expected)
+	assert.NoError(t, err)
+
+	// In compatible mode number_of_members comes from the diagnostic data collector.
+	count := testutil.CollectAndCount(metricsCollector(replsetConfigMetrics(cfg, status, true)),
+		"mongodb_mongod_replset_number_of_members")
+	assert.Equal(t, 0, count)
+}
+
+func TestReplsetMemberMetrics(t *testing.T) {
+	status := bson.M{
+		"set": "rs1",
+		"members": primitive.A{
+			bson.M{
+				"_id":      int32(0),
+				"name":     "rs101:27017",
+				"stateStr": "PRIMARY",
+				"self":     true,
+			},
+			bson.M{
+				"_id":           int32(1),
+				"name":          "rs102:27017",
+				"stateStr":      "SECONDARY",
+				"lastHeartbeat": primitive.NewDateTimeFromTime(time.Unix(1700000000, 0)),
+				"pingMs":        int64(2),
+			},
+			bson.M{
+				"_id":           int32(2),
+				"name":          "rs103:27017",
+				"stateStr":      "SECONDARY",
+				"lastHeartbeat": primitive.NewDateTimeFromTime(time.Unix(1700000001, 500000000)),
+				"pingMs":        int64(15),
+			},
+			bson.M{
+				"_id":           int32(3),
+				"name":          "rs104:27017",
+				"stateStr":      "(not reachable/healthy)",
+				"lastHeartbeat": primitive.DateTime(0),
+			},
+		},
+	}
+
+	expected := strings.NewReader(`
+	# HELP mongodb_mongod_replset_member_last_heartbeat_seconds Unix timestamp of the last heartbeat received from the member.
+	# TYPE mongodb_mongod_replset_member_last_heartbeat_seconds gauge
+	mongodb_mongod_replset_member_last_heartbeat_seconds{name="rs102:27017",set="rs1"} 1.7e+09
+	mongodb_mongod_replset_member_last_heartbeat_seconds{name="rs103:27017",set="rs1"} 1.7000000015e+09
+	# HELP mongodb_mongod_replset_member_ping_ms Round trip time in milliseconds between the current member and the member.
+	# TYPE mongodb_mongod_replset_member_ping_ms gauge
+	mongodb_mongod_replset_member_ping_ms{name="rs102:27017",set="rs1"} 2
+	mongodb_mongod_replset_member_ping_ms{name="rs103:27017",set="rs1"} 15` + "\n")
+
+	err := testutil.CollectAndCompare(metricsCollector(replsetMemberMetrics(status, false)), expected)
+	assert.NoError(t, err)
+
+	// The compatible replica set metrics of the diagnostic data collector have the same ping time
+	// metric with other labels so both cannot be registered together.
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(metricsCollector(replSetMetrics(bson.M{"replSetGetStatus": status})))
+	assert.Error(t, registry.Register(metricsCollector(replsetMemberMetrics(status, false))))
+	assert.NoError(t, registry.Register(metricsCollector(replsetMemberMetrics(status, true))))
+}
@@ -0,0 +1,77 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// statusWriter replaces the 200 status of the response by status. Other statuses, like the
+// errors written by promhttp, are kept.
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	if code == http.StatusOK {
+		code = w.status
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
+// gatheredMetrics returns a gatherer returning metrics already gathered.
+func gatheredMetrics(mfs []*dto.MetricFamily, err error) prometheus.Gatherer {
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		return mfs, err
+	})
+}
+
+// mongodbDown returns true if mongodb_up is 0 in the gathered metrics.
+func mongodbDown(mfs []*dto.MetricFamily) bool {
+	for _, mf := range mfs {
+		if mf.GetName() != "mongodb_up" {
+			continue
+		}
+
+		for _, m := range mf.GetMetric() {
+			if m.GetGauge().GetValue() == 0 {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+var _ http.ResponseWriter = (*statusWriter)(nil)
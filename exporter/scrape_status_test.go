@@ -0,0 +1,49 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFailScrapeOnDown(t *testing.T) {
+	for _, failScrapeOnDown := range []bool{false, true} {
+		e := New(&Opts{
+			URI:                      "mongodb://127.0.0.1:12345",
+			Logger:                   logrus.New(),
+			ServerSelectionTimeoutMS: 100,
+			DisableDefaultRegistry:   true,
+			FailScrapeOnDown:         failScrapeOnDown,
+		})
+
+		rr := httptest.NewRecorder()
+		e.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+
+		expectedCode := http.StatusOK
+		if failScrapeOnDown {
+			expectedCode = http.StatusServiceUnavailable
+		}
+		assert.Equal(t, expectedCode, rr.Code)
+		assert.Contains(t, rr.Body.String(), "mongodb_up 0\n")
+		assert.Contains(t, rr.Body.String(), "collector_scrape_time_ms")
+		assert.Contains(t, rr.Header().Get("Content-Type"), "text/plain")
+	}
+}
@@ -0,0 +1,109 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type securityCollector struct {
+	ctx  context.Context
+	base *baseCollector
+}
+
+// newSecurityCollector creates a collector for the authentications per mechanism reported by serverStatus.
+func newSecurityCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger) *securityCollector {
+	return &securityCollector{
+		ctx:  ctx,
+		base: newBaseCollector(client, logger),
+	}
+}
+
+func (d *securityCollector) Describe(ch chan<- *prometheus.Desc) {
+	d.base.Describe(d.ctx, ch, d.collect)
+}
+
+func (d *securityCollector) Collect(ch chan<- prometheus.Metric) {
+	d.base.Collect(ch)
+}
+
+func (d *securityCollector) collect(ch chan<- prometheus.Metric) {
+	defer measureCollectTime(ch, "mongodb", "security")()
+
+	logger := d.base.logger
+
+	m, err := serverStatus(d.ctx, d.base.client)
+	if err != nil {
+		logger.Errorf("cannot get security stats: %s", err)
+
+		return
+	}
+
+	metrics := authenticationMetrics(m)
+	if len(metrics) == 0 {
+		logger.Debug("serverStatus has no authentication mechanisms stats")
+
+		return
+	}
+
+	for _, metric := range metrics {
+		ch <- metric
+	}
+}
+
+// authenticationMetrics returns the received and successful authentications per mechanism, like
+// SCRAM-SHA-256 or MONGODB-X509, from the security section of a serverStatus document.
+// A growing gap between them can reveal credential stuffing or misconfigured clients.
+func authenticationMetrics(m bson.M) []prometheus.Metric {
+	mechanisms, ok := walkTo(m, []string{"security", "authentication", "mechanisms"}).(bson.M)
+	if !ok {
+		return nil
+	}
+
+	desc := prometheus.NewDesc("mongodb_security_authentication_mechanisms_total",
+		"Number of authentications received and successful per mechanism.", []string{"mechanism", "result"}, nil)
+
+	var metrics []prometheus.Metric
+
+	for mechanism, v := range mechanisms {
+		mechanismStats, ok := v.(bson.M)
+		if !ok {
+			continue
+		}
+
+		authenticate, ok := mechanismStats["authenticate"].(bson.M)
+		if !ok {
+			continue
+		}
+
+		for _, result := range []string{"received", "successful"} {
+			f, err := asFloat64(authenticate[result])
+			if err != nil || f == nil {
+				continue
+			}
+			metrics = append(metrics, prometheus.MustNewConstMetric(desc, prometheus.CounterValue, *f, mechanism, result))
+		}
+	}
+
+	return metrics
+}
+
+var _ prometheus.Collector = (*securityCollector)(nil)
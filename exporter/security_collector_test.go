@@ -0,0 +1,65 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestAuthenticationMetrics(t *testing.T) {
+	m := bson.M{
+		"security": bson.M{
+			"authentication": bson.M{
+				"saslSupportedMechsReceived": int64(12),
+				"mechanisms": bson.M{
+					"MONGODB-X509": bson.M{
+						"speculativeAuthenticate": bson.M{"received": int64(0), "successful": int64(0)},
+						"authenticate":            bson.M{"received": int64(3), "successful": int64(3)},
+					},
+					"SCRAM-SHA-1": bson.M{
+						"speculativeAuthenticate": bson.M{"received": int64(1), "successful": int64(1)},
+						"authenticate":            bson.M{"received": int64(5), "successful": int64(2)},
+					},
+					"SCRAM-SHA-256": bson.M{
+						"speculativeAuthenticate": bson.M{"received": int64(40), "successful": int64(38)},
+						"authenticate":            bson.M{"received": int64(1250), "successful": int64(48)},
+					},
+				},
+			},
+		},
+	}
+
+	expected := `
+	# HELP mongodb_security_authentication_mechanisms_total Number of authentications received and successful per mechanism.
+	# TYPE mongodb_security_authentication_mechanisms_total counter
+	mongodb_security_authentication_mechanisms_total{mechanism="MONGODB-X509",result="received"} 3
+	mongodb_security_authentication_mechanisms_total{mechanism="MONGODB-X509",result="successful"} 3
+	mongodb_security_authentication_mechanisms_total{mechanism="SCRAM-SHA-1",result="received"} 5
+	mongodb_security_authentication_mechanisms_total{mechanism="SCRAM-SHA-1",result="successful"} 2
+	mongodb_security_authentication_mechanisms_total{mechanism="SCRAM-SHA-256",result="received"} 1250
+	mongodb_security_authentication_mechanisms_total{mechanism="SCRAM-SHA-256",result="successful"} 48` + "\n"
+
+	err := testutil.CollectAndCompare(metricsCollector(authenticationMetrics(m)), strings.NewReader(expected))
+	assert.NoError(t, err)
+
+	// The security section is missing without the privileges to see it.
+	assert.Empty(t, authenticationMetrics(bson.M{"ok": float64(1)}))
+}
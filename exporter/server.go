@@ -16,17 +16,26 @@
 package exporter
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"net/http"
+	"net/http/pprof"
 	"net/url"
 	"os"
 	"strings"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/prometheus/common/promlog"
 	"github.com/prometheus/exporter-toolkit/web"
 	"github.com/sirupsen/logrus"
 )
 
+var (
+	errTLSKeyPair       = errors.New("the TLS certificate and key files must be set together")
+	errTLSWithWebConfig = errors.New("the TLS certificate and key files cannot be used with the web config file")
+)
+
 // ServerMap stores http handlers for each host
 type ServerMap map[string]http.Handler
 
@@ -36,16 +45,119 @@ type ServerOpts struct {
 	MultiTargetPath  string
 	WebListenAddress string
 	TLSConfigPath    string
+	// EnablePprof exposes the net/http/pprof handlers under /debug/pprof/ to profile the exporter.
+	EnablePprof bool
+	// TLSCertFile and TLSKeyFile are the certificate and the key used to serve over HTTPS.
+	// They cannot be used with TLSConfigPath.
+	TLSCertFile string
+	TLSKeyFile  string
+	// TLSClientCAFile, if set, makes the server require client certificates signed by these CAs.
+	TLSClientCAFile string
+	// BasicListenAddress is the address of a second server exposing only the basic metrics
+	// on Path. It is not started if empty.
+	BasicListenAddress string
 }
 
 // Runs the main web-server
 func RunWebServer(opts *ServerOpts, exporters []*Exporter, log *logrus.Logger) {
-	mux := http.DefaultServeMux
-
 	if len(exporters) == 0 {
 		panic("No exporters were built. You must specify --mongodb.uri command argument or MONGODB_URI environment variable")
 	}
 
+	var tlsConfig *tls.Config
+	if opts.TLSCertFile != "" || opts.TLSKeyFile != "" {
+		var err error
+		if tlsConfig, err = serverTLSConfig(opts); err != nil {
+			log.Errorf("invalid TLS configuration: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	if opts.BasicListenAddress != "" {
+		go listenAndServe(opts.BasicListenAddress, opts.TLSConfigPath, tlsConfig, buildBasicMux(opts, exporters), log)
+	}
+
+	listenAndServe(opts.WebListenAddress, opts.TLSConfigPath, tlsConfig, buildMux(opts, exporters, log), log)
+}
+
+func listenAndServe(address, tlsConfigPath string, tlsConfig *tls.Config, handler http.Handler, log *logrus.Logger) {
+	server := &http.Server{
+		Addr:              address,
+		ReadHeaderTimeout: 2 * time.Second,
+		Handler:           handler,
+		TLSConfig:         tlsConfig,
+	}
+
+	if tlsConfig != nil {
+		// The certificates are already in the TLS config.
+		if err := server.ListenAndServeTLS("", ""); err != nil {
+			log.Errorf("error starting server: %v", err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	flags := &web.FlagConfig{
+		WebListenAddresses: &[]string{address},
+		WebConfigFile:      &tlsConfigPath,
+	}
+	if err := web.ListenAndServe(server, flags, promlog.New(&promlog.Config{})); err != nil {
+		log.Errorf("error starting server: %v", err)
+		os.Exit(1)
+	}
+}
+
+// serverTLSConfig returns the TLS configuration to serve over HTTPS with the certificate and
+// the key files. Client certificates are required if the client CA file is set.
+func serverTLSConfig(opts *ServerOpts) (*tls.Config, error) {
+	if opts.TLSCertFile == "" || opts.TLSKeyFile == "" {
+		return nil, errTLSKeyPair
+	}
+
+	if opts.TLSConfigPath != "" {
+		return nil, errTLSWithWebConfig
+	}
+
+	cert, err := tls.LoadX509KeyPair(opts.TLSCertFile, opts.TLSKeyFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot load the TLS certificate")
+	}
+
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if opts.TLSClientCAFile != "" {
+		pem, err := os.ReadFile(opts.TLSClientCAFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot read the client CA file")
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("no certificate found in the client CA file %s", opts.TLSClientCAFile)
+		}
+
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return cfg, nil
+}
+
+// buildBasicMux returns the handlers of the server exposing only the basic metrics.
+func buildBasicMux(opts *ServerOpts, exporters []*Exporter) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle(opts.Path, exporters[0].BasicHandler())
+
+	return mux
+}
+
+func buildMux(opts *ServerOpts, exporters []*Exporter, log *logrus.Logger) *http.ServeMux {
+	mux := http.NewServeMux()
+
 	serverMap := buildServerMap(exporters, log)
 
 	defaultExporter := exporters[0]
@@ -65,18 +177,15 @@ func RunWebServer(opts *ServerOpts, exporters []*Exporter, log *logrus.Logger) {
 		}
 	})
 
-	server := &http.Server{
-		ReadHeaderTimeout: 2 * time.Second,
-		Handler:           mux,
-	}
-	flags := &web.FlagConfig{
-		WebListenAddresses: &[]string{opts.WebListenAddress},
-		WebConfigFile:      &opts.TLSConfigPath,
-	}
-	if err := web.ListenAndServe(server, flags, promlog.New(&promlog.Config{})); err != nil {
-		log.Errorf("error starting server: %v", err)
-		os.Exit(1)
+	if opts.EnablePprof {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
 	}
+
+	return mux
 }
 
 func multiTargetHandler(serverMap ServerMap) http.HandlerFunc {
@@ -88,7 +197,8 @@ func multiTargetHandler(serverMap ServerMap) http.HandlerFunc {
 			}
 			if uri, err := url.Parse(targetHost); err == nil {
 				if e, ok := serverMap[uri.Host]; ok {
-					e.ServeHTTP(w, r)
+					ctx := withTargetName(r.Context(), r.URL.Query().Get("name"))
+					e.ServeHTTP(w, r.WithContext(ctx))
 					return
 				}
 			}
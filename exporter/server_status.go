@@ -0,0 +1,72 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type serverStatusCacheKey struct{}
+
+// serverStatusCache holds the serverStatus document for a single scrape so all the
+// collectors reading from serverStatus run the command only once.
+type serverStatusCache struct {
+	once sync.Once
+	doc  bson.M
+	err  error
+}
+
+// withServerStatusCache returns a context carrying an empty serverStatus cache.
+// makeRegistry uses it so every collector built for a scrape shares the same document.
+func withServerStatusCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, serverStatusCacheKey{}, &serverStatusCache{})
+}
+
+// serverStatus returns the serverStatus document, reusing the one cached in the context if
+// it is available.
+func serverStatus(ctx context.Context, client *mongo.Client) (bson.M, error) {
+	cache, ok := ctx.Value(serverStatusCacheKey{}).(*serverStatusCache)
+	if !ok {
+		return runServerStatus(ctx, client)
+	}
+
+	cache.once.Do(func() {
+		cache.doc, cache.err = runServerStatus(ctx, client)
+	})
+
+	// The command only ran for the first collector but the others are missing the same metrics.
+	if isUnauthorized(cache.err) {
+		notePermissionError(ctx)
+	}
+
+	return cache.doc, cache.err
+}
+
+func runServerStatus(ctx context.Context, client *mongo.Client) (bson.M, error) {
+	var m bson.M
+
+	cmd := bson.D{{Key: "serverStatus", Value: 1}}
+	if err := client.Database("admin").RunCommand(ctx, cmd).Decode(&m); err != nil {
+		return nil, errors.Wrap(err, "cannot run serverStatus")
+	}
+
+	return m, nil
+}
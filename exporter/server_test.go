@@ -0,0 +1,203 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/expfmt"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPprofHandlers(t *testing.T) {
+	log := logrus.New()
+	exporters := []*Exporter{New(&Opts{URI: "mongodb://127.0.0.1:12345", Logger: log})}
+
+	for _, enabled := range []bool{false, true} {
+		opts := &ServerOpts{
+			Path:            "/metrics",
+			MultiTargetPath: "/scrape",
+			EnablePprof:     enabled,
+		}
+		mux := buildMux(opts, exporters, log)
+
+		_, pattern := mux.Handler(httptest.NewRequest("GET", "/debug/pprof/profile", nil))
+		if enabled {
+			assert.Equal(t, "/debug/pprof/profile", pattern)
+		} else {
+			assert.Equal(t, "/", pattern)
+		}
+
+		// pprof must not replace the other handlers.
+		_, pattern = mux.Handler(httptest.NewRequest("GET", "/metrics", nil))
+		assert.Equal(t, "/metrics", pattern)
+	}
+}
+
+func TestBasicHandler(t *testing.T) {
+	log := logrus.New()
+	e := New(&Opts{
+		URI:                      "mongodb://127.0.0.1:12345",
+		Logger:                   log,
+		ServerSelectionTimeoutMS: 100,
+	})
+
+	rr := httptest.NewRecorder()
+	e.BasicHandler().ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(rr.Body)
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	assert.ElementsMatch(t, []string{"mongodb_up", "mongodb_exporter_build_info"}, names)
+	assert.Equal(t, float64(0), families["mongodb_up"].GetMetric()[0].GetGauge().GetValue())
+}
+
+// writeCert writes a PEM certificate and its key signed by parent, or self-signed if parent is nil.
+func writeCert(t *testing.T, dir, name string, tmpl *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".crt"), certPEM, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".key"), keyPEM, 0o600))
+
+	return cert, key
+}
+
+func TestServeTLS(t *testing.T) {
+	dir := t.TempDir()
+	notAfter := time.Now().Add(time.Hour)
+
+	ca, caKey := writeCert(t, dir, "ca", &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}, nil, nil)
+	writeCert(t, dir, "server", &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotAfter:     notAfter,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca, caKey)
+	writeCert(t, dir, "client", &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "prometheus"},
+		NotAfter:     notAfter,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca, caKey)
+
+	opts := &ServerOpts{
+		Path:            "/metrics",
+		MultiTargetPath: "/scrape",
+		TLSCertFile:     filepath.Join(dir, "server.crt"),
+		TLSKeyFile:      filepath.Join(dir, "server.key"),
+		TLSClientCAFile: filepath.Join(dir, "ca.crt"),
+	}
+
+	tlsConfig, err := serverTLSConfig(opts)
+	require.NoError(t, err)
+
+	log := logrus.New()
+	exporters := []*Exporter{New(&Opts{URI: "mongodb://127.0.0.1:12345", Logger: log})}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := &http.Server{
+		Handler:           buildMux(opts, exporters, log),
+		TLSConfig:         tlsConfig,
+		ReadHeaderTimeout: time.Second,
+	}
+	go server.ServeTLS(ln, "", "") //nolint:errcheck
+	defer server.Close()           //nolint:errcheck
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	clientCert, err := tls.LoadX509KeyPair(filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key"))
+	require.NoError(t, err)
+
+	get := func(certs ...tls.Certificate) (*http.Response, error) {
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs, MinVersion: tls.VersionTLS12},
+		}}
+
+		return client.Get("https://" + ln.Addr().String() + "/")
+	}
+
+	resp, err := get(clientCert)
+	require.NoError(t, err)
+	resp.Body.Close() //nolint:errcheck
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Clients without a certificate signed by the client CA are rejected.
+	_, err = get()
+	assert.Error(t, err)
+
+	t.Run("Invalid options", func(t *testing.T) {
+		_, err := serverTLSConfig(&ServerOpts{TLSCertFile: opts.TLSCertFile})
+		assert.ErrorIs(t, err, errTLSKeyPair)
+
+		_, err = serverTLSConfig(&ServerOpts{
+			TLSCertFile:   opts.TLSCertFile,
+			TLSKeyFile:    opts.TLSKeyFile,
+			TLSConfigPath: filepath.Join(dir, "web-config.yml"),
+		})
+		assert.ErrorIs(t, err, errTLSWithWebConfig)
+	})
+}
@@ -0,0 +1,229 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// shardStatusFunc returns the replSetGetStatus of the primary of the shard replica set. host is the
+// shard connection string of config.shards, like rs1/h1:27018,h2:27018.
+type shardStatusFunc func(ctx context.Context, host string) (bson.M, error)
+
+// shard is a shard of config.shards.
+type shard struct {
+	name string
+	host string
+}
+
+type shardReplLagCollector struct {
+	ctx          context.Context
+	base         *baseCollector
+	configClient *mongo.Client
+	shardStatus  shardStatusFunc
+}
+
+// newShardReplLagCollector creates a collector for the replication lag of the members of every shard.
+// If configClient is not nil, config.shards is read from it instead of through client.
+func newShardReplLagCollector(ctx context.Context, client, configClient *mongo.Client, logger *logrus.Logger, shardStatus shardStatusFunc) *shardReplLagCollector {
+	if configClient == nil {
+		configClient = client
+	}
+
+	return &shardReplLagCollector{
+		ctx:          ctx,
+		base:         newBaseCollector(client, logger),
+		configClient: configClient,
+		shardStatus:  shardStatus,
+	}
+}
+
+func (d *shardReplLagCollector) Describe(ch chan<- *prometheus.Desc) {
+	d.base.Describe(d.ctx, ch, d.collect)
+}
+
+func (d *shardReplLagCollector) Collect(ch chan<- prometheus.Metric) {
+	d.base.Collect(ch)
+}
+
+func (d *shardReplLagCollector) collect(ch chan<- prometheus.Metric) {
+	defer measureCollectTime(ch, "mongodb", "shardrepllag")()
+
+	logger := d.base.logger
+
+	opts := options.Find().SetProjection(bson.M{"_id": 1, "host": 1})
+	cursor, err := d.configClient.Database("config").Collection("shards").Find(d.ctx, bson.M{}, opts)
+	if err != nil {
+		if isUnauthorized(err) {
+			logger.Debugf("not allowed to read config.shards: %s", err)
+
+			return
+		}
+		logger.Errorf("cannot get the shards: %s", err)
+
+		return
+	}
+
+	var docs []bson.M
+	if err := cursor.All(d.ctx, &docs); err != nil {
+		logger.Errorf("cannot decode the shards: %s", err)
+
+		return
+	}
+
+	for _, metric := range shardLagMetrics(d.ctx, shardsFromConfig(docs), d.shardStatus, logger) {
+		ch <- metric
+	}
+}
+
+// shardsFromConfig returns the shards of the config.shards documents, sorted by name.
+func shardsFromConfig(docs []bson.M) []shard {
+	shards := make([]shard, 0, len(docs))
+	for _, doc := range docs {
+		name, _ := doc["_id"].(string)
+		host, _ := doc["host"].(string)
+		if name == "" || host == "" {
+			continue
+		}
+
+		shards = append(shards, shard{name: name, host: host})
+	}
+
+	sort.Slice(shards, func(i, j int) bool { return shards[i].name < shards[j].name })
+
+	return shards
+}
+
+// shardLagMetrics gets the replica set status of the shards, at most fanOutConcurrency at a time, and
+// returns the replication lag of their members. An unreachable shard is logged and skipped so it
+// doesn't fail the scrape.
+func shardLagMetrics(ctx context.Context, shards []shard, shardStatus shardStatusFunc, logger *logrus.Logger) []prometheus.Metric {
+	results := make([][]prometheus.Metric, len(shards))
+	sem := make(chan struct{}, fanOutConcurrency)
+
+	d := prometheus.NewDesc("mongodb_shard_replset_member_lag_seconds",
+		"Replication lag of the shard replica set member behind the primary.", []string{"shard", "member"}, nil)
+
+	var wg sync.WaitGroup
+	for i, s := range shards {
+		wg.Add(1)
+
+		go func(i int, s shard) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			shardCtx, cancel := context.WithTimeout(ctx, fanOutMemberTimeout)
+			defer cancel()
+
+			status, err := shardStatus(shardCtx, s.host)
+			if err != nil {
+				logger.Warnf("cannot get the replica set status of the shard %s: %s", s.name, err)
+
+				return
+			}
+
+			for _, lag := range memberLags(status) {
+				results[i] = append(results[i], prometheus.MustNewConstMetric(d, prometheus.GaugeValue, lag.seconds, s.name, lag.member))
+			}
+		}(i, s)
+	}
+
+	wg.Wait()
+
+	var metrics []prometheus.Metric
+	for _, r := range results {
+		metrics = append(metrics, r...)
+	}
+
+	return metrics
+}
+
+type memberLag struct {
+	member  string
+	seconds float64
+}
+
+// memberLags returns how far behind the primary the secondaries of a replSetGetStatus response are,
+// based on the optime of their last applied operation. There is no lag without a primary.
+func memberLags(status bson.M) []memberLag {
+	members, ok := status["members"].(primitive.A)
+	if !ok {
+		return nil
+	}
+
+	var primary primitive.DateTime
+	var secondaries []bson.M
+	for _, m := range members {
+		member, ok := m.(bson.M)
+		if !ok {
+			continue
+		}
+
+		switch member["stateStr"] {
+		case "PRIMARY":
+			primary, _ = member["optimeDate"].(primitive.DateTime)
+		case "SECONDARY":
+			secondaries = append(secondaries, member)
+		}
+	}
+
+	if primary == 0 {
+		return nil
+	}
+
+	lags := make([]memberLag, 0, len(secondaries))
+	for _, member := range secondaries {
+		name, _ := member["name"].(string)
+		optime, ok := member["optimeDate"].(primitive.DateTime)
+		if name == "" || !ok {
+			continue
+		}
+
+		seconds := primary.Time().Sub(optime.Time()).Seconds()
+		if seconds < 0 {
+			// The secondary heartbeat can be more recent than the one of the primary.
+			seconds = 0
+		}
+		lags = append(lags, memberLag{member: name, seconds: seconds})
+	}
+
+	return lags
+}
+
+// parseShardHost returns the replica set name and the hosts of a config.shards connection string.
+// The replica set name is empty for a standalone shard.
+func parseShardHost(host string) (string, []string) {
+	replicaSet, hosts, ok := strings.Cut(host, "/")
+	if !ok {
+		return "", strings.Split(host, ",")
+	}
+
+	return replicaSet, strings.Split(hosts, ",")
+}
+
+var _ prometheus.Collector = (*shardReplLagCollector)(nil)
@@ -0,0 +1,92 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestShardLagMetrics(t *testing.T) {
+	docs := []bson.M{
+		{"_id": "rs2", "host": "rs2/rs2-1:27018,rs2-2:27018", "state": int32(1)},
+		{"_id": "rs1", "host": "rs1/rs1-1:27018,rs1-2:27018,rs1-3:27018", "state": int32(1)},
+		{"_id": "rs3", "host": "rs3/rs3-1:27018", "state": int32(1)},
+	}
+	shards := shardsFromConfig(docs)
+	assert.Equal(t, []shard{
+		{name: "rs1", host: "rs1/rs1-1:27018,rs1-2:27018,rs1-3:27018"},
+		{name: "rs2", host: "rs2/rs2-1:27018,rs2-2:27018"},
+		{name: "rs3", host: "rs3/rs3-1:27018"},
+	}, shards)
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	optime := func(lag time.Duration) primitive.DateTime {
+		return primitive.NewDateTimeFromTime(now.Add(-lag))
+	}
+
+	shardStatus := func(ctx context.Context, host string) (bson.M, error) {
+		switch host {
+		case "rs1/rs1-1:27018,rs1-2:27018,rs1-3:27018":
+			return bson.M{
+				"set": "rs1",
+				"members": primitive.A{
+					bson.M{"name": "rs1-1:27018", "stateStr": "PRIMARY", "optimeDate": optime(0)},
+					bson.M{"name": "rs1-2:27018", "stateStr": "SECONDARY", "optimeDate": optime(2 * time.Second)},
+					// Arbiters don't replicate.
+					bson.M{"name": "rs1-3:27018", "stateStr": "ARBITER"},
+				},
+			}, nil
+		case "rs2/rs2-1:27018,rs2-2:27018":
+			return bson.M{
+				"set": "rs2",
+				"members": primitive.A{
+					bson.M{"name": "rs2-1:27018", "stateStr": "SECONDARY", "optimeDate": optime(90 * time.Second)},
+					bson.M{"name": "rs2-2:27018", "stateStr": "PRIMARY", "optimeDate": optime(500 * time.Millisecond)},
+				},
+			}, nil
+		}
+
+		return nil, errors.New("server selection timeout")
+	}
+
+	expected := `
+	# HELP mongodb_shard_replset_member_lag_seconds Replication lag of the shard replica set member behind the primary.
+	# TYPE mongodb_shard_replset_member_lag_seconds gauge
+	mongodb_shard_replset_member_lag_seconds{member="rs1-2:27018",shard="rs1"} 2
+	mongodb_shard_replset_member_lag_seconds{member="rs2-1:27018",shard="rs2"} 89.5` + "\n"
+
+	metrics := shardLagMetrics(context.Background(), shards, shardStatus, logrus.New())
+	err := testutil.CollectAndCompare(metricsCollector(metrics), strings.NewReader(expected))
+	assert.NoError(t, err)
+
+	replicaSet, hosts := parseShardHost("rs1/rs1-1:27018,rs1-2:27018")
+	assert.Equal(t, "rs1", replicaSet)
+	assert.Equal(t, []string{"rs1-1:27018", "rs1-2:27018"}, hosts)
+
+	replicaSet, hosts = parseShardHost("standalone:27018")
+	assert.Empty(t, replicaSet)
+	assert.Equal(t, []string{"standalone:27018"}, hosts)
+}
@@ -28,17 +28,27 @@ import (
 )
 
 type shardsCollector struct {
-	ctx        context.Context
-	base       *baseCollector
-	compatible bool
+	ctx          context.Context
+	base         *baseCollector
+	configClient *mongo.Client
+	compatible   bool
+	namespaces   []string
 }
 
 // newShardsCollector creates collector collecting metrics about chunks for shards Mongo.
-func newShardsCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger, compatibleMode bool) *shardsCollector {
+// If configClient is not nil, the config database is read from it instead of through client.
+// If namespaces is not empty, only the chunks of these sharded collections are counted.
+func newShardsCollector(ctx context.Context, client, configClient *mongo.Client, logger *logrus.Logger, compatibleMode bool, namespaces []string) *shardsCollector {
+	if configClient == nil {
+		configClient = client
+	}
+
 	return &shardsCollector{
-		ctx:        ctx,
-		base:       newBaseCollector(client, logger),
-		compatible: compatibleMode,
+		ctx:          ctx,
+		base:         newBaseCollector(client, logger),
+		configClient: configClient,
+		compatible:   compatibleMode,
+		namespaces:   removeEmptyStrings(namespaces),
 	}
 }
 
@@ -55,40 +65,90 @@ func (d *shardsCollector) collect(ch chan<- prometheus.Metric) {
 
 	client := d.base.client
 	logger := d.base.logger
-	prefix := "shards collection chunks"
 
 	databaseNames, err := client.ListDatabaseNames(d.ctx, bson.D{})
 	if err != nil {
 		logger.Errorf("cannot get database names: %s", err)
 	}
 	for _, database := range databaseNames {
+		if !d.includesDatabase(database) {
+			continue
+		}
+
 		collections := d.getCollectionsForDBName(database)
-		for _, row := range collections {
-			if len(row) == 0 {
-				continue
-			}
+		for _, metric := range d.chunksMetrics(database, collections, d.getChunksForCollection) {
+			ch <- metric
+		}
+	}
+}
 
-			var ok bool
-			if _, ok = row["_id"]; !ok {
-				continue
-			}
-			var rowID string
-			if rowID, ok = row["_id"].(string); !ok {
+// chunksMetrics returns the number of chunks per shard of the sharded collections of the database.
+// collections are the documents of config.collections and getChunks returns the chunks per
+// shard of one of them.
+func (d *shardsCollector) chunksMetrics(database string, collections []primitive.M, getChunks func(primitive.M) []bson.M) []prometheus.Metric {
+	prefix := "shards collection chunks"
+
+	var metrics []prometheus.Metric
+
+	for _, row := range collections {
+		if len(row) == 0 {
+			continue
+		}
+
+		var ok bool
+		if _, ok = row["_id"]; !ok {
+			continue
+		}
+		var rowID string
+		if rowID, ok = row["_id"].(string); !ok {
+			continue
+		}
+
+		if !d.includesCollection(rowID) {
+			continue
+		}
+
+		chunks := getChunks(row)
+		for _, c := range chunks {
+			labels, chunks, success := d.getInfoForChunk(c, database, rowID)
+			if !success {
 				continue
 			}
+			metrics = append(metrics, makeMetrics(prefix, primitive.M{"count": chunks}, labels, d.compatible)...)
+		}
+	}
 
-			chunks := d.getChunksForCollection(row)
-			for _, c := range chunks {
-				labels, chunks, success := d.getInfoForChunk(c, database, rowID)
-				if !success {
-					continue
-				}
-				for _, metric := range makeMetrics(prefix, primitive.M{"count": chunks}, labels, d.compatible) {
-					ch <- metric
-				}
-			}
+	return metrics
+}
+
+// includesDatabase returns true if the database has some of the namespaces to collect.
+func (d *shardsCollector) includesDatabase(database string) bool {
+	if len(d.namespaces) == 0 {
+		return true
+	}
+
+	for _, ns := range d.namespaces {
+		if db, _ := splitNamespace(ns); db == database {
+			return true
 		}
 	}
+
+	return false
+}
+
+// includesCollection returns true if the chunks of the namespace must be collected.
+func (d *shardsCollector) includesCollection(namespace string) bool {
+	if len(d.namespaces) == 0 {
+		return true
+	}
+
+	for _, ns := range d.namespaces {
+		if ns == namespace {
+			return true
+		}
+	}
+
+	return false
 }
 
 func (d *shardsCollector) getInfoForChunk(c primitive.M, database, rowID string) (map[string]string, int32, bool) {
@@ -128,7 +188,7 @@ func (d *shardsCollector) getInfoForChunk(c primitive.M, database, rowID string)
 }
 
 func (d *shardsCollector) getCollectionsForDBName(database string) []primitive.M {
-	client := d.base.client
+	client := d.configClient
 	logger := d.base.logger
 
 	cursor := client.Database("config").Collection("collections")
@@ -167,7 +227,7 @@ func (d *shardsCollector) getChunksForCollection(row primitive.M) []bson.M {
 		bson.M{"$sort": bson.M{"shard": 1}},
 	}
 
-	client := d.base.client
+	client := d.configClient
 	logger := d.base.logger
 
 	cur, err := client.Database("config").Collection("chunks").Aggregate(context.Background(), aggregation)
@@ -22,8 +22,12 @@ import (
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"github.com/percona/mongodb_exporter/internal/tu"
 )
@@ -35,7 +39,7 @@ func TestShardsCollector(t *testing.T) {
 	defer cancel()
 
 	client := tu.DefaultTestClientMongoS(ctx, t)
-	c := newShardsCollector(ctx, client, logrus.New(), false)
+	c := newShardsCollector(ctx, client, nil, logrus.New(), false, nil)
 
 	reg := prometheus.NewPedanticRegistry()
 	if err := reg.Register(c); err != nil {
@@ -67,3 +71,58 @@ func TestShardsCollector(t *testing.T) {
 		assert.Contains(t, res, v)
 	}
 }
+
+func TestShardsCollectorNamespaces(t *testing.T) {
+	collections := []primitive.M{
+		{"_id": "test.orders"},
+		{"_id": "test.users"},
+		{"_id": "test.events"},
+	}
+
+	chunks := map[string][]bson.M{
+		"test.orders": {{"shard": "rs1", "nChunks": int32(10)}, {"shard": "rs2", "nChunks": int32(12)}},
+		"test.users":  {{"shard": "rs1", "nChunks": int32(3)}},
+		"test.events": {{"shard": "rs2", "nChunks": int32(40)}},
+	}
+	getChunks := func(row primitive.M) []bson.M {
+		return chunks[row["_id"].(string)] //nolint:forcetypeassert
+	}
+
+	collectionLabels := func(metrics []prometheus.Metric) []string {
+		var res []string
+		for _, m := range metrics {
+			var pb dto.Metric
+			require.NoError(t, m.Write(&pb))
+			for _, l := range pb.GetLabel() {
+				if l.GetName() == "collection" {
+					res = append(res, l.GetValue())
+				}
+			}
+		}
+
+		return res
+	}
+
+	c := newShardsCollector(context.Background(), nil, nil, logrus.New(), false, []string{"test.orders", "test.events", ""})
+	assert.True(t, c.includesDatabase("test"))
+	assert.False(t, c.includesDatabase("other"))
+	assert.ElementsMatch(t, []string{"orders", "orders", "events"},
+		collectionLabels(c.chunksMetrics("test", collections, getChunks)))
+
+	// Without namespaces, all the sharded collections are collected.
+	c = newShardsCollector(context.Background(), nil, nil, logrus.New(), false, []string{""})
+	assert.True(t, c.includesDatabase("other"))
+	assert.ElementsMatch(t, []string{"orders", "orders", "users", "events"},
+		collectionLabels(c.chunksMetrics("test", collections, getChunks)))
+}
+
+func TestShardsCollectorState(t *testing.T) {
+	e := &Exporter{opts: &Opts{EnableShards: true}}
+	requestOpts := Opts{EnableShards: true}
+
+	assert.True(t, collectorEnabled(e.collectorStates(typeMongos, requestOpts), collectorShards))
+	assert.False(t, collectorEnabled(e.collectorStates(typeMongod, requestOpts), collectorShards))
+
+	e.opts.ShardCollStatsNamespaces = []string{"test.orders"}
+	assert.True(t, collectorEnabled(e.collectorStates(typeMongod, requestOpts), collectorShards))
+}
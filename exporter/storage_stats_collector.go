@@ -0,0 +1,198 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type storageStatsCollector struct {
+	ctx  context.Context
+	base *baseCollector
+}
+
+// newStorageStatsCollector creates a collector for storage related stats like the fsync lock state,
+// the storage engine checkpoints and the WiredTiger tickets.
+func newStorageStatsCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger) *storageStatsCollector {
+	return &storageStatsCollector{
+		ctx:  ctx,
+		base: newBaseCollector(client, logger),
+	}
+}
+
+func (d *storageStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	d.base.Describe(d.ctx, ch, d.collect)
+}
+
+func (d *storageStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	d.base.Collect(ch)
+}
+
+func (d *storageStatsCollector) collect(ch chan<- prometheus.Metric) {
+	defer measureCollectTime(ch, "mongodb", "storage_stats")()
+
+	logger := d.base.logger
+	client := d.base.client
+
+	// The fsync lock state is reported at the top level of the currentOp response.
+	// Filter by a non existent operation id to not get the list of operations in progress.
+	var currentOp bson.M
+	cmd := bson.D{{Key: "currentOp", Value: 1}, {Key: "opid", Value: -1}}
+	switch err := client.Database("admin").RunCommand(d.ctx, cmd).Decode(&currentOp); {
+	case isUnauthorized(err):
+		logger.Debugf("not allowed to get the fsync lock state, the inprog privilege is required: %s", err)
+	case err != nil:
+		logger.Errorf("cannot get fsync lock state: %s", err)
+	default:
+		ch <- fsyncLockMetric(currentOp)
+	}
+
+	status, err := serverStatus(d.ctx, client)
+	if err != nil {
+		logger.Errorf("cannot get checkpoint stats: %s", err)
+
+		return
+	}
+
+	for _, metric := range checkpointMetrics(status) {
+		ch <- metric
+	}
+
+	for _, metric := range ticketMetrics(status) {
+		ch <- metric
+	}
+}
+
+// checkpointStats are the paths in serverStatus of the checkpoint counters of a storage engine.
+type checkpointStats struct {
+	count      []string
+	durationMS []string
+}
+
+// engineCheckpointStats maps the checkpoint stats of each storage engine to the same metrics.
+// WiredTiger moved them from the transaction section to their own section in recent versions
+// and MMAPv1 reports the background flushes of the data files instead.
+var engineCheckpointStats = []checkpointStats{ //nolint:gochecknoglobals
+	{
+		count:      []string{"wiredTiger", "checkpoint", "number of checkpoints"},
+		durationMS: []string{"wiredTiger", "checkpoint", "most recent time (msecs)"},
+	},
+	{
+		count:      []string{"wiredTiger", "transaction", "transaction checkpoints"},
+		durationMS: []string{"wiredTiger", "transaction", "transaction checkpoint most recent time (msecs)"},
+	},
+	{
+		count:      []string{"backgroundFlushing", "flushes"},
+		durationMS: []string{"backgroundFlushing", "last_ms"},
+	},
+}
+
+// checkpointMetrics returns the number of checkpoints and the duration of the last one from a
+// serverStatus document. Nothing is returned for engines without checkpoints like inMemory.
+func checkpointMetrics(m bson.M) []prometheus.Metric {
+	for _, stats := range engineCheckpointStats {
+		count, err := asFloat64(walkTo(m, stats.count))
+		if err != nil || count == nil {
+			continue
+		}
+
+		d := prometheus.NewDesc("mongodb_storage_checkpoint_total",
+			"Number of checkpoints taken by the storage engine.", nil, nil)
+		metrics := []prometheus.Metric{prometheus.MustNewConstMetric(d, prometheus.CounterValue, *count)}
+
+		if ms, err := asFloat64(walkTo(m, stats.durationMS)); err == nil && ms != nil {
+			d := prometheus.NewDesc("mongodb_storage_checkpoint_duration_seconds",
+				"Duration of the most recent checkpoint in seconds.", nil, nil)
+			metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.GaugeValue, *ms/1000))
+		}
+
+		return metrics
+	}
+
+	return nil
+}
+
+// fsyncLockMetric returns mongodb_fsync_locked from a currentOp response. It is 0 when the server
+// is not locked since in that case the fsyncLock field is not in the response.
+func fsyncLockMetric(m bson.M) prometheus.Metric { //nolint:ireturn
+	var locked float64
+	if v, ok := m["fsyncLock"].(bool); ok && v {
+		locked = 1
+	}
+
+	d := prometheus.NewDesc("mongodb_fsync_locked", "Whether the server is locked by fsyncLock.", nil, nil)
+
+	return prometheus.MustNewConstMetric(d, prometheus.GaugeValue, locked)
+}
+
+// ticketMetrics returns the read and write tickets limiting the concurrent WiredTiger transactions
+// from a serverStatus document. MongoDB 7.0 moved them from wiredTiger.concurrentTransactions to
+// queues.execution. Nothing is returned for the other storage engines.
+func ticketMetrics(m bson.M) []prometheus.Metric {
+	tickets, ok := walkTo(m, []string{"queues", "execution"}).(bson.M)
+	if !ok {
+		if tickets, ok = walkTo(m, []string{"wiredTiger", "concurrentTransactions"}).(bson.M); !ok {
+			return nil
+		}
+	}
+
+	fields := []struct {
+		field string
+		desc  *prometheus.Desc
+	}{
+		{
+			field: "available",
+			desc: prometheus.NewDesc("mongodb_wiredtiger_concurrent_transactions_available_tickets",
+				"Number of tickets available for concurrent transactions.", []string{"type"}, nil),
+		},
+		{
+			field: "out",
+			desc: prometheus.NewDesc("mongodb_wiredtiger_concurrent_transactions_out_tickets",
+				"Number of tickets in use by concurrent transactions.", []string{"type"}, nil),
+		},
+		{
+			field: "totalTickets",
+			desc: prometheus.NewDesc("mongodb_wiredtiger_concurrent_transactions_total_tickets",
+				"Total number of tickets for concurrent transactions.", []string{"type"}, nil),
+		},
+	}
+
+	var metrics []prometheus.Metric
+
+	for _, ticketType := range []string{"read", "write"} {
+		t, ok := tickets[ticketType].(bson.M)
+		if !ok {
+			continue
+		}
+
+		for _, f := range fields {
+			v, err := asFloat64(t[f.field])
+			if err != nil || v == nil {
+				continue
+			}
+			metrics = append(metrics, prometheus.MustNewConstMetric(f.desc, prometheus.GaugeValue, *v, ticketType))
+		}
+	}
+
+	return metrics
+}
+
+var _ prometheus.Collector = (*storageStatsCollector)(nil)
@@ -0,0 +1,174 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestFsyncLockMetric(t *testing.T) {
+	t.Run("Locked", func(t *testing.T) {
+		m := bson.M{
+			"inprog":    bson.A{},
+			"fsyncLock": true,
+			"info":      "use db.fsyncUnlock() to terminate the fsync write/snapshot lock",
+			"ok":        float64(1),
+		}
+
+		expected := strings.NewReader(`
+		# HELP mongodb_fsync_locked Whether the server is locked by fsyncLock.
+		# TYPE mongodb_fsync_locked gauge
+		mongodb_fsync_locked 1` + "\n")
+
+		err := testutil.CollectAndCompare(metricsCollector([]prometheus.Metric{fsyncLockMetric(m)}), expected)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Not locked", func(t *testing.T) {
+		m := bson.M{"inprog": bson.A{}, "ok": float64(1)}
+
+		// The fsyncLock field is only in the response when the server is locked.
+		expected := strings.NewReader(`
+		# HELP mongodb_fsync_locked Whether the server is locked by fsyncLock.
+		# TYPE mongodb_fsync_locked gauge
+		mongodb_fsync_locked 0` + "\n")
+
+		err := testutil.CollectAndCompare(metricsCollector([]prometheus.Metric{fsyncLockMetric(m)}), expected)
+		assert.NoError(t, err)
+	})
+}
+
+func TestCheckpointMetrics(t *testing.T) {
+	expected := `
+	# HELP mongodb_storage_checkpoint_duration_seconds Duration of the most recent checkpoint in seconds.
+	# TYPE mongodb_storage_checkpoint_duration_seconds gauge
+	mongodb_storage_checkpoint_duration_seconds 1.5
+	# HELP mongodb_storage_checkpoint_total Number of checkpoints taken by the storage engine.
+	# TYPE mongodb_storage_checkpoint_total counter
+	mongodb_storage_checkpoint_total 42` + "\n"
+
+	tests := []struct {
+		name string
+		m    bson.M
+	}{
+		{
+			name: "WiredTiger checkpoint section",
+			m: bson.M{
+				"wiredTiger": bson.M{
+					"checkpoint": bson.M{
+						"number of checkpoints":    int64(42),
+						"most recent time (msecs)": int64(1500),
+						"total time (msecs)":       int64(60000),
+					},
+				},
+			},
+		},
+		{
+			name: "WiredTiger transaction section",
+			m: bson.M{
+				"wiredTiger": bson.M{
+					"transaction": bson.M{
+						"transaction checkpoints":                         int32(42),
+						"transaction checkpoint most recent time (msecs)": int32(1500),
+					},
+				},
+			},
+		},
+		{
+			name: "MMAPv1 background flushing",
+			m: bson.M{
+				"backgroundFlushing": bson.M{
+					"flushes":    int32(42),
+					"total_ms":   int32(63000),
+					"average_ms": 1500.0,
+					"last_ms":    int32(1500),
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			err := testutil.CollectAndCompare(metricsCollector(checkpointMetrics(tt.m)), strings.NewReader(expected))
+			assert.NoError(t, err)
+		})
+	}
+
+	t.Run("No checkpoints", func(t *testing.T) {
+		m := bson.M{
+			"storageEngine": bson.M{"name": "inMemory"},
+			"ok":            float64(1),
+		}
+		assert.Empty(t, checkpointMetrics(m))
+	})
+}
+
+func TestTicketMetrics(t *testing.T) {
+	expected := `
+	# HELP mongodb_wiredtiger_concurrent_transactions_available_tickets Number of tickets available for concurrent transactions.
+	# TYPE mongodb_wiredtiger_concurrent_transactions_available_tickets gauge
+	mongodb_wiredtiger_concurrent_transactions_available_tickets{type="read"} 126
+	mongodb_wiredtiger_concurrent_transactions_available_tickets{type="write"} 127
+	# HELP mongodb_wiredtiger_concurrent_transactions_out_tickets Number of tickets in use by concurrent transactions.
+	# TYPE mongodb_wiredtiger_concurrent_transactions_out_tickets gauge
+	mongodb_wiredtiger_concurrent_transactions_out_tickets{type="read"} 2
+	mongodb_wiredtiger_concurrent_transactions_out_tickets{type="write"} 1
+	# HELP mongodb_wiredtiger_concurrent_transactions_total_tickets Total number of tickets for concurrent transactions.
+	# TYPE mongodb_wiredtiger_concurrent_transactions_total_tickets gauge
+	mongodb_wiredtiger_concurrent_transactions_total_tickets{type="read"} 128
+	mongodb_wiredtiger_concurrent_transactions_total_tickets{type="write"} 128` + "\n"
+
+	tickets := bson.M{
+		"read":  bson.M{"out": int32(2), "available": int32(126), "totalTickets": int32(128)},
+		"write": bson.M{"out": int32(1), "available": int32(127), "totalTickets": int32(128)},
+	}
+
+	tests := []struct {
+		name string
+		m    bson.M
+	}{
+		{
+			name: "wiredTiger.concurrentTransactions",
+			m:    bson.M{"wiredTiger": bson.M{"concurrentTransactions": tickets}},
+		},
+		{
+			name: "MongoDB 7.0 queues.execution",
+			m: bson.M{
+				"queues":     bson.M{"execution": tickets},
+				"wiredTiger": bson.M{"cache": bson.M{"bytes currently in the cache": int64(1024)}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			err := testutil.CollectAndCompare(metricsCollector(ticketMetrics(tt.m)), strings.NewReader(expected))
+			assert.NoError(t, err)
+		})
+	}
+
+	t.Run("Not WiredTiger", func(t *testing.T) {
+		assert.Empty(t, ticketMetrics(bson.M{"storageEngine": bson.M{"name": "inMemory"}}))
+	})
+}
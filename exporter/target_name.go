@@ -0,0 +1,94 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/AlekSi/pointer"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// labelTargetName is the label with the logical name of the target in multi-target mode.
+const labelTargetName = "name"
+
+type targetNameKey struct{}
+
+// withTargetName returns a context carrying the name of the target requested on /scrape.
+func withTargetName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, targetNameKey{}, name)
+}
+
+// targetName returns the name of the target of the scrape and true if it is a multi-target scrape.
+func targetName(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(targetNameKey{}).(string)
+
+	return name, ok
+}
+
+// sanitizeTargetName replaces the characters other than letters, digits, and _ . : - by _ so
+// names given in the scrape URL are usable in dashboards and alerts.
+func sanitizeTargetName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case r == '_', r == '.', r == ':', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+// targetNameGatherer adds the name label to the metrics returned by the wrapped gatherer.
+// Metrics already having a name label, like the replica set members, keep their own.
+type targetNameGatherer struct {
+	gatherer prometheus.Gatherer
+	name     string
+}
+
+func (g *targetNameGatherer) Gather() ([]*dto.MetricFamily, error) {
+	mfs, err := g.gatherer.Gather()
+
+	for _, mf := range mfs {
+		for _, m := range mf.GetMetric() {
+			if hasLabel(m, labelTargetName) {
+				continue
+			}
+
+			m.Label = append(m.Label, &dto.LabelPair{Name: pointer.ToString(labelTargetName), Value: pointer.ToString(g.name)})
+			sort.Slice(m.Label, func(i, j int) bool { return m.Label[i].GetName() < m.Label[j].GetName() })
+		}
+	}
+
+	return mfs, err
+}
+
+func hasLabel(m *dto.Metric, name string) bool {
+	for _, l := range m.GetLabel() {
+		if l.GetName() == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+var _ prometheus.Gatherer = (*targetNameGatherer)(nil)
@@ -0,0 +1,208 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+)
+
+// tlsFileOptions are the URI options, in lower case, holding the path of a TLS file loaded when the
+// client is built.
+var tlsFileOptions = map[string]struct{}{ //nolint:gochecknoglobals
+	"tlscertificatekeyfile":       {},
+	"tlscafile":                   {},
+	"sslclientcertificatekeyfile": {},
+	"sslcertificateauthorityfile": {},
+}
+
+// tlsFiles returns the TLS files of the URI and of the config server URI, like the client
+// certificate and the CA.
+func tlsFiles(opts *Opts) ([]string, error) {
+	files, err := uriTLSFiles(opts.URI, opts.DriverOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.ConfigServerURI == "" {
+		return files, nil
+	}
+
+	cfgFiles, err := uriTLSFiles(opts.ConfigServerURI, opts.DriverOptions)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config server URI: %w", err)
+	}
+
+	return unique(append(files, cfgFiles...)), nil
+}
+
+func uriTLSFiles(rawURI string, driverOptions map[string]string) ([]string, error) {
+	uri, err := expandURI(rawURI)
+	if err != nil {
+		return nil, err
+	}
+
+	uri, err = withDriverOptions(uri, driverOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	_, rawQuery, ok := strings.Cut(uri, "?")
+	if !ok {
+		return nil, nil
+	}
+
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid URI options")
+	}
+
+	var files []string
+	for key, values := range query {
+		if _, ok := tlsFileOptions[strings.ToLower(key)]; !ok {
+			continue
+		}
+
+		for _, file := range values {
+			if file != "" {
+				files = append(files, filepath.Clean(file))
+			}
+		}
+	}
+
+	return files, nil
+}
+
+// tlsChangeDebounce is how long the TLS files must stay unchanged before the clients are rebuilt
+// so a rotation writing several files, or several events for a file, rebuilds them once.
+const tlsChangeDebounce = 500 * time.Millisecond
+
+// watchTLSFiles calls onChange, debounce after the last change, when one of the files is written,
+// replaced or removed. The directories are watched instead of the files since editors and tools
+// like cert-manager replace the files with an atomic rename, which would end a watch on the file
+// itself. Kubernetes Secret volumes rotate the files by swapping the ..data symlink of the
+// directory, which reports events for ..data only, so a file is also changed when the target of
+// its symlinks is. The returned watcher must be closed to stop watching.
+func watchTLSFiles(files []string, debounce time.Duration, onChange func(file string), onError func(err error)) (*fsnotify.Watcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create the TLS files watcher")
+	}
+
+	targets := make(map[string]string, len(files))
+	for _, file := range files {
+		targets[file] = resolveSymlinks(file)
+
+		if err := watcher.Add(filepath.Dir(file)); err != nil {
+			_ = watcher.Close()
+
+			return nil, errors.Wrapf(err, "cannot watch %s", file)
+		}
+	}
+
+	go func() {
+		var changed string
+		var reload <-chan time.Time
+
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+
+				if event.Op == fsnotify.Chmod {
+					continue
+				}
+
+				for file, target := range targets {
+					newTarget := resolveSymlinks(file)
+					if filepath.Clean(event.Name) != file && newTarget == target {
+						continue
+					}
+
+					targets[file] = newTarget
+					changed = file
+					reload = time.After(debounce)
+				}
+			case <-reload:
+				reload = nil
+				onChange(changed)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				onError(err)
+			}
+		}
+	}()
+
+	return watcher, nil
+}
+
+// resolveSymlinks returns the file the path points to or an empty string if it doesn't exist.
+func resolveSymlinks(path string) string {
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return ""
+	}
+
+	return target
+}
+
+// startTLSWatcher makes the next scrape rebuild the global connection pool client and the config
+// servers client, and so reload their TLS files, when the files change.
+func (e *Exporter) startTLSWatcher() error {
+	files, err := tlsFiles(e.opts)
+	if err != nil {
+		return err
+	}
+
+	if len(files) == 0 {
+		e.logger.Warn("There are no TLS files to watch in the URI")
+
+		return nil
+	}
+
+	onChange := func(file string) {
+		e.logger.Infof("The TLS file %s changed, the MongoDB clients will be rebuilt", file)
+
+		e.clientMu.Lock()
+		e.clientHealthy = false
+		configClient := e.configClient
+		e.configClient = nil
+		e.clientMu.Unlock()
+
+		e.disconnectAsync(configClient)
+	}
+	onError := func(err error) {
+		e.logger.Errorf("Error while watching the TLS files: %v", err)
+	}
+
+	watcher, err := watchTLSFiles(files, tlsChangeDebounce, onChange, onError)
+	if err != nil {
+		return err
+	}
+	e.tlsWatcher = watcher
+
+	return nil
+}
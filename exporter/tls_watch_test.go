@@ -0,0 +1,179 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestTLSFiles(t *testing.T) {
+	files, err := tlsFiles(&Opts{
+		URI:           "mongodb://127.0.0.1:27017/admin?tls=true&tlsCAFile=/etc/ssl/../ssl/ca.pem",
+		DriverOptions: map[string]string{"tlsCertificateKeyFile": "/var/run/secrets/client.pem"},
+	})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"/etc/ssl/ca.pem", "/var/run/secrets/client.pem"}, files)
+
+	// The config servers client can have its own TLS files.
+	files, err = tlsFiles(&Opts{
+		URI:             "mongodb://127.0.0.1:27017/admin?tls=true&tlsCAFile=/etc/ssl/ca.pem",
+		ConfigServerURI: "mongodb://cfg1:27019/?tls=true&tlsCAFile=/etc/ssl/ca.pem&tlsCertificateKeyFile=/etc/ssl/cfg.pem",
+	})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"/etc/ssl/ca.pem", "/etc/ssl/cfg.pem"}, files)
+
+	files, err = tlsFiles(&Opts{URI: "mongodb://127.0.0.1:27017"})
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestWatchTLSFiles(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "client.pem")
+	require.NoError(t, os.WriteFile(certFile, []byte("old certificate"), 0o600))
+
+	// New is not used since its initial connection would race with the client set by the test.
+	e := &Exporter{
+		opts: &Opts{
+			URI:                      "mongodb://127.0.0.1:12345/admin?tls=true&tlsCertificateKeyFile=" + certFile,
+			ServerSelectionTimeoutMS: 100,
+			GlobalConnPool:           true,
+			WatchTLSFiles:            true,
+		},
+		logger: logrus.New(),
+	}
+	require.NoError(t, e.startTLSWatcher())
+
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI("mongodb://127.0.0.1:12345"))
+	require.NoError(t, err)
+
+	healthy := func() bool {
+		e.clientMu.Lock()
+		defer e.clientMu.Unlock()
+
+		return e.clientHealthy
+	}
+	setClient := func() {
+		e.clientMu.Lock()
+		defer e.clientMu.Unlock()
+
+		e.client = client
+		e.clientHealthy = true
+	}
+	configClient := func() *mongo.Client {
+		e.clientMu.Lock()
+		defer e.clientMu.Unlock()
+
+		return e.configClient
+	}
+
+	// Tools like cert-manager replace the file with an atomic rename.
+	setClient()
+	tmpFile := filepath.Join(dir, ".client.pem.tmp")
+	require.NoError(t, os.WriteFile(tmpFile, []byte("new certificate"), 0o600))
+	require.NoError(t, os.Rename(tmpFile, certFile))
+	assert.Eventually(t, func() bool { return !healthy() }, 5*time.Second, 10*time.Millisecond)
+
+	// The file is still watched after the rename.
+	setClient()
+	require.NoError(t, os.WriteFile(certFile, []byte("newer certificate"), 0o600))
+	assert.Eventually(t, func() bool { return !healthy() }, 5*time.Second, 10*time.Millisecond)
+
+	// The config servers client is dropped too.
+	cfgClient, err := mongo.Connect(ctx, options.Client().ApplyURI("mongodb://127.0.0.1:12345"))
+	require.NoError(t, err)
+	e.clientMu.Lock()
+	e.configClient = cfgClient
+	e.clientMu.Unlock()
+	require.NoError(t, os.WriteFile(certFile, []byte("config certificate"), 0o600))
+	assert.Eventually(t, func() bool { return configClient() == nil }, 5*time.Second, 10*time.Millisecond)
+
+	// Other files of the directory are ignored.
+	setClient()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "other.pem"), []byte("other"), 0o600))
+	time.Sleep(2 * tlsChangeDebounce)
+	assert.True(t, healthy())
+
+	// The next scrape disconnects the old client and builds a new one with the new files.
+	setClient()
+	require.NoError(t, os.WriteFile(certFile, []byte("not a certificate"), 0o600))
+	assert.Eventually(t, func() bool { return !healthy() }, 5*time.Second, 10*time.Millisecond)
+	_, err = e.getClient(ctx)
+	assert.ErrorContains(t, err, "failed to find CERTIFICATE")
+	assert.Nil(t, e.client)
+
+	require.NoError(t, e.Shutdown(ctx))
+	assert.Nil(t, e.tlsWatcher)
+}
+
+func TestWatchTLSFilesSymlinks(t *testing.T) {
+	// The layout of a Kubernetes Secret volume: the files are symlinks to ..data/<file> and
+	// ..data is a symlink to the directory of the current version.
+	dir := t.TempDir()
+	writeVersion := func(version, content string) {
+		require.NoError(t, os.Mkdir(filepath.Join(dir, version), 0o700))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, version, "tls.crt"), []byte(content), 0o600))
+	}
+	writeVersion("..2024_01_01", "old certificate")
+	require.NoError(t, os.Symlink("..2024_01_01", filepath.Join(dir, "..data")))
+	certFile := filepath.Join(dir, "tls.crt")
+	require.NoError(t, os.Symlink(filepath.Join("..data", "tls.crt"), certFile))
+
+	changes := make(chan string, 10)
+	watcher, err := watchTLSFiles([]string{certFile}, 50*time.Millisecond,
+		func(file string) { changes <- file },
+		func(err error) { t.Error(err) })
+	require.NoError(t, err)
+	defer watcher.Close() //nolint:errcheck
+
+	// The rotation swaps the ..data symlink, reporting events for ..data_tmp and ..data only.
+	writeVersion("..2024_02_01", "new certificate")
+	require.NoError(t, os.Symlink("..2024_02_01", filepath.Join(dir, "..data_tmp")))
+	require.NoError(t, os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")))
+
+	select {
+	case file := <-changes:
+		assert.Equal(t, certFile, file)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the swap of the ..data symlink was not detected")
+	}
+
+	// The events of the rotation are debounced into a single change.
+	time.Sleep(200 * time.Millisecond)
+	assert.Empty(t, changes)
+
+	// A plain rewrite of the file is detected too.
+	require.NoError(t, os.Remove(certFile))
+	require.NoError(t, os.WriteFile(certFile, []byte("regular file"), 0o600))
+
+	select {
+	case file := <-changes:
+		assert.Equal(t, certFile, file)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the rewrite of the file was not detected")
+	}
+}
@@ -139,7 +139,12 @@ func (t *topologyInfo) loadLabels(ctx context.Context) error {
 
 func getNodeType(ctx context.Context, client *mongo.Client) (mongoDBNodeType, error) {
 	md := proto.MasterDoc{}
-	if err := client.Database("admin").RunCommand(ctx, primitive.M{"isMaster": 1}).Decode(&md); err != nil {
+	err := client.Database("admin").RunCommand(ctx, primitive.M{"isMaster": 1}).Decode(&md)
+	if isAPIStrictError(err) {
+		// isMaster is not in the Stable API, hello is and it has the same msg and arbiterOnly fields.
+		err = client.Database("admin").RunCommand(ctx, primitive.M{"hello": 1}).Decode(&md)
+	}
+	if err != nil {
 		return "", err
 	}
 
@@ -164,6 +169,11 @@ func getClusterRole(ctx context.Context, client *mongo.Client) (string, error) {
 	})
 
 	if res.Err() != nil {
+		// getCmdLineOpts is not in the Stable API.
+		if isAPIStrictError(res.Err()) {
+			return clusterRoleFromHello(ctx, client), nil
+		}
+
 		return "", nil
 	}
 
@@ -182,3 +192,28 @@ func getClusterRole(ctx context.Context, client *mongo.Client) (string, error) {
 
 	return clusterRole, nil
 }
+
+// clusterRoleFromHello returns the cluster role found with the hello command, which is in the
+// Stable API. Only mongos and the config servers can be told apart, shard servers are reported
+// without a role.
+func clusterRoleFromHello(ctx context.Context, client *mongo.Client) string {
+	var hello primitive.M
+	if err := client.Database("admin").RunCommand(ctx, primitive.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		return ""
+	}
+
+	return helloClusterRole(hello)
+}
+
+func helloClusterRole(hello primitive.M) string {
+	if msg, _ := hello["msg"].(string); msg == typeIsDBGrid {
+		return string(typeMongos)
+	}
+
+	// Config servers have a configsvr field.
+	if _, ok := hello["configsvr"]; ok {
+		return "configsvr"
+	}
+
+	return ""
+}
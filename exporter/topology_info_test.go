@@ -24,6 +24,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"github.com/percona/mongodb_exporter/internal/tu"
 )
@@ -141,3 +142,9 @@ func TestGetClusterRole(t *testing.T) {
 		assert.Equal(t, tc.want, nodeType, fmt.Sprintf("container name: %s, port: %s", tc.containerName, port))
 	}
 }
+
+func TestHelloClusterRole(t *testing.T) {
+	assert.Equal(t, string(typeMongos), helloClusterRole(primitive.M{"msg": "isdbgrid", "ok": float64(1)}))
+	assert.Equal(t, "configsvr", helloClusterRole(primitive.M{"setName": "cnf", "configsvr": int32(2), "ok": float64(1)}))
+	assert.Equal(t, "", helloClusterRole(primitive.M{"setName": "rs1", "ok": float64(1)}))
+}
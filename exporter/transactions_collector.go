@@ -0,0 +1,191 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type transactionsCollector struct {
+	ctx  context.Context
+	base *baseCollector
+}
+
+// newTransactionsCollector creates a collector for the multi-document transactions reported by serverStatus.
+func newTransactionsCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger) *transactionsCollector {
+	return &transactionsCollector{
+		ctx:  ctx,
+		base: newBaseCollector(client, logger),
+	}
+}
+
+func (d *transactionsCollector) Describe(ch chan<- *prometheus.Desc) {
+	d.base.Describe(d.ctx, ch, d.collect)
+}
+
+func (d *transactionsCollector) Collect(ch chan<- prometheus.Metric) {
+	d.base.Collect(ch)
+}
+
+func (d *transactionsCollector) collect(ch chan<- prometheus.Metric) {
+	defer measureCollectTime(ch, "mongodb", "transactions")()
+
+	logger := d.base.logger
+
+	m, err := serverStatus(d.ctx, d.base.client)
+	if err != nil {
+		logger.Errorf("cannot get transaction stats: %s", err)
+
+		return
+	}
+
+	for _, metric := range transactionsMetrics(m) {
+		ch <- metric
+	}
+
+	// Standalone servers don't support transactions.
+	if _, ok := m["transactions"]; !ok {
+		return
+	}
+
+	ops, err := openTransactions(d.ctx, d.base.client)
+	if err != nil {
+		if isUnauthorized(err) {
+			logger.Debugf("not allowed to get the open transactions, the inprog privilege is required: %s", err)
+
+			return
+		}
+		logger.Errorf("cannot get the open transactions: %s", err)
+
+		return
+	}
+
+	ch <- oldestTransactionMetric(ops)
+}
+
+// openTransactions returns the $currentOp entries of the open transactions, including the idle ones.
+func openTransactions(ctx context.Context, client *mongo.Client) ([]bson.M, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$currentOp", Value: bson.D{{Key: "allUsers", Value: true}, {Key: "idleSessions", Value: true}}}},
+		{{Key: "$match", Value: bson.D{{Key: "transaction", Value: bson.D{{Key: "$exists", Value: true}}}}}},
+		{{Key: "$project", Value: bson.D{{Key: "transaction.timeOpenMicros", Value: 1}}}},
+	}
+
+	cursor, err := client.Database("admin").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot run $currentOp")
+	}
+
+	var ops []bson.M
+	if err := cursor.All(ctx, &ops); err != nil {
+		return nil, errors.Wrap(err, "cannot read the $currentOp results")
+	}
+
+	return ops, nil
+}
+
+// oldestTransactionMetric returns the age of the oldest open transaction from $currentOp entries, or
+// 0 if there is no open transaction.
+func oldestTransactionMetric(ops []bson.M) prometheus.Metric { //nolint:ireturn
+	var oldest float64
+	for _, op := range ops {
+		transaction, ok := op["transaction"].(bson.M)
+		if !ok {
+			continue
+		}
+
+		f, err := asFloat64(transaction["timeOpenMicros"])
+		if err != nil || f == nil {
+			continue
+		}
+
+		if age := *f / 1e6; age > oldest {
+			oldest = age
+		}
+	}
+
+	d := prometheus.NewDesc("mongodb_transactions_oldest_active_seconds",
+		"Age of the oldest open transaction, 0 if there is no open transaction.", nil, nil)
+
+	return prometheus.MustNewConstMetric(d, prometheus.GaugeValue, oldest)
+}
+
+// transactionsMetrics returns the transaction counters of the transactions section of a serverStatus
+// document. The section only exists on replica set members and mongos since MongoDB 4.0 so no metrics
+// are returned when it is absent.
+func transactionsMetrics(m bson.M) []prometheus.Metric {
+	transactions, ok := m["transactions"].(bson.M)
+	if !ok {
+		return nil
+	}
+
+	var metrics []prometheus.Metric
+
+	totalDesc := prometheus.NewDesc("mongodb_transactions_total",
+		"Number of transactions per state since the server started.", []string{"state"}, nil)
+
+	for _, c := range []struct {
+		field string
+		state string
+	}{
+		{field: "totalStarted", state: "started"},
+		{field: "totalCommitted", state: "committed"},
+		{field: "totalAborted", state: "aborted"},
+	} {
+		f, err := asFloat64(transactions[c.field])
+		if err != nil || f == nil {
+			continue
+		}
+		metrics = append(metrics, prometheus.MustNewConstMetric(totalDesc, prometheus.CounterValue, *f, c.state))
+	}
+
+	for _, g := range []struct {
+		field string
+		desc  *prometheus.Desc
+	}{
+		{
+			field: "currentActive",
+			desc: prometheus.NewDesc("mongodb_transactions_active",
+				"Number of transactions running a command.", nil, nil),
+		},
+		{
+			field: "currentOpen",
+			desc: prometheus.NewDesc("mongodb_transactions_open",
+				"Number of open transactions, running a command or idle between commands.", nil, nil),
+		},
+		{
+			field: "currentPrepared",
+			desc: prometheus.NewDesc("mongodb_transactions_prepared",
+				"Number of prepared transactions, waiting for the commit or the abort of a distributed transaction.", nil, nil),
+		},
+	} {
+		f, err := asFloat64(transactions[g.field])
+		if err != nil || f == nil {
+			continue
+		}
+		metrics = append(metrics, prometheus.MustNewConstMetric(g.desc, prometheus.GaugeValue, *f))
+	}
+
+	return metrics
+}
+
+var _ prometheus.Collector = (*transactionsCollector)(nil)
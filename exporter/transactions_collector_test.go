@@ -0,0 +1,98 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestTransactionsMetrics(t *testing.T) {
+	m := bson.M{
+		"transactions": bson.M{
+			"retriedCommandsCount":       int64(0),
+			"currentActive":              int64(2),
+			"currentInactive":            int64(1),
+			"currentOpen":                int64(3),
+			"totalAborted":               int64(14),
+			"totalCommitted":             int64(1520),
+			"totalStarted":               int64(1537),
+			"totalPrepared":              int64(0),
+			"currentPrepared":            int64(0),
+			"totalPreparedThenAborted":   int64(0),
+			"totalPreparedThenCommitted": int64(0),
+			"commitTypes": bson.M{
+				"singleShard": bson.M{
+					"initiated":  int64(1500),
+					"successful": int64(1499),
+				},
+			},
+		},
+	}
+
+	expected := `
+	# HELP mongodb_transactions_active Number of transactions running a command.
+	# TYPE mongodb_transactions_active gauge
+	mongodb_transactions_active 2
+	# HELP mongodb_transactions_open Number of open transactions, running a command or idle between commands.
+	# TYPE mongodb_transactions_open gauge
+	mongodb_transactions_open 3
+	# HELP mongodb_transactions_prepared Number of prepared transactions, waiting for the commit or the abort of a distributed transaction.
+	# TYPE mongodb_transactions_prepared gauge
+	mongodb_transactions_prepared 0
+	# HELP mongodb_transactions_total Number of transactions per state since the server started.
+	# TYPE mongodb_transactions_total counter
+	mongodb_transactions_total{state="aborted"} 14
+	mongodb_transactions_total{state="committed"} 1520
+	mongodb_transactions_total{state="started"} 1537` + "\n"
+
+	err := testutil.CollectAndCompare(metricsCollector(transactionsMetrics(m)), strings.NewReader(expected))
+	assert.NoError(t, err)
+
+	// Standalone instances have no transactions section.
+	assert.Empty(t, transactionsMetrics(bson.M{"ok": float64(1)}))
+}
+
+func TestOldestTransactionMetric(t *testing.T) {
+	ops := []bson.M{
+		{"transaction": bson.M{"timeOpenMicros": int64(1500000)}},
+		{"transaction": bson.M{"timeOpenMicros": int64(93250000)}},
+		{"transaction": bson.M{"timeOpenMicros": int64(200)}},
+		// Entries without the field are skipped.
+		{"transaction": bson.M{}},
+	}
+
+	expected := `
+	# HELP mongodb_transactions_oldest_active_seconds Age of the oldest open transaction, 0 if there is no open transaction.
+	# TYPE mongodb_transactions_oldest_active_seconds gauge
+	mongodb_transactions_oldest_active_seconds 93.25` + "\n"
+
+	err := testutil.CollectAndCompare(metricsCollector([]prometheus.Metric{oldestTransactionMetric(ops)}), strings.NewReader(expected))
+	assert.NoError(t, err)
+
+	expected = `
+	# HELP mongodb_transactions_oldest_active_seconds Age of the oldest open transaction, 0 if there is no open transaction.
+	# TYPE mongodb_transactions_oldest_active_seconds gauge
+	mongodb_transactions_oldest_active_seconds 0` + "\n"
+
+	err = testutil.CollectAndCompare(metricsCollector([]prometheus.Metric{oldestTransactionMetric(nil)}), strings.NewReader(expected))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,110 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ttlCollector struct {
+	ctx  context.Context
+	base *baseCollector
+}
+
+// newTTLCollector creates a collector for the documents deleted by the TTL monitor.
+func newTTLCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger) *ttlCollector {
+	return &ttlCollector{
+		ctx:  ctx,
+		base: newBaseCollector(client, logger),
+	}
+}
+
+func (d *ttlCollector) Describe(ch chan<- *prometheus.Desc) {
+	d.base.Describe(d.ctx, ch, d.collect)
+}
+
+func (d *ttlCollector) Collect(ch chan<- prometheus.Metric) {
+	d.base.Collect(ch)
+}
+
+func (d *ttlCollector) collect(ch chan<- prometheus.Metric) {
+	defer measureCollectTime(ch, "mongodb", "ttl")()
+
+	logger := d.base.logger
+
+	m, err := serverStatus(d.ctx, d.base.client)
+	if err != nil {
+		logger.Errorf("cannot get TTL stats: %s", err)
+
+		return
+	}
+
+	metrics := ttlMetrics(m)
+	if metrics == nil {
+		logger.Debug("serverStatus has no metrics.ttl section")
+	}
+
+	for _, metric := range metrics {
+		ch <- metric
+	}
+}
+
+// ttlMetrics returns the counters of the metrics.ttl section of a serverStatus document. No metrics
+// are returned if the server doesn't report the section.
+func ttlMetrics(m bson.M) []prometheus.Metric {
+	serverMetrics, ok := m["metrics"].(bson.M)
+	if !ok {
+		return nil
+	}
+
+	ttl, ok := serverMetrics["ttl"].(bson.M)
+	if !ok {
+		return nil
+	}
+
+	var metrics []prometheus.Metric
+
+	for _, c := range []struct {
+		field string
+		desc  *prometheus.Desc
+	}{
+		{
+			field: "deletedDocuments",
+			desc: prometheus.NewDesc("mongodb_metrics_ttl_deleted_documents_total",
+				"Number of documents deleted from collections with a TTL index.", nil, nil),
+		},
+		{
+			field: "passes",
+			desc: prometheus.NewDesc("mongodb_metrics_ttl_passes_total",
+				"Number of passes of the TTL monitor over the collections with a TTL index.", nil, nil),
+		},
+	} {
+		f, err := asFloat64(ttl[c.field])
+		if err != nil || f == nil {
+			continue
+		}
+		metrics = append(metrics, prometheus.MustNewConstMetric(c.desc, prometheus.CounterValue, *f))
+	}
+
+	return metrics
+}
+
+var _ prometheus.Collector = (*ttlCollector)(nil)
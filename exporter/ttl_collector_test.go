@@ -0,0 +1,52 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestTTLMetrics(t *testing.T) {
+	m := bson.M{
+		"metrics": bson.M{
+			"ttl": bson.M{
+				"deletedDocuments": int64(52341),
+				"passes":           int64(1440),
+				"subPasses":        int64(1440),
+			},
+		},
+	}
+
+	expected := `
+	# HELP mongodb_metrics_ttl_deleted_documents_total Number of documents deleted from collections with a TTL index.
+	# TYPE mongodb_metrics_ttl_deleted_documents_total counter
+	mongodb_metrics_ttl_deleted_documents_total 52341
+	# HELP mongodb_metrics_ttl_passes_total Number of passes of the TTL monitor over the collections with a TTL index.
+	# TYPE mongodb_metrics_ttl_passes_total counter
+	mongodb_metrics_ttl_passes_total 1440` + "\n"
+
+	err := testutil.CollectAndCompare(metricsCollector(ttlMetrics(m)), strings.NewReader(expected))
+	assert.NoError(t, err)
+
+	// Older servers may not report the ttl section.
+	assert.Empty(t, ttlMetrics(bson.M{"metrics": bson.M{"document": bson.M{}}}))
+	assert.Empty(t, ttlMetrics(bson.M{"ok": float64(1)}))
+}
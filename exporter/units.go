@@ -0,0 +1,101 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// unitConversion renames a metric and multiplies its values by factor.
+type unitConversion struct {
+	name   string
+	factor float64
+}
+
+// normalizedUnits maps the metrics reported in milliseconds, microseconds or megabytes to their
+// name in seconds or bytes when Opts.NormalizeUnits is enabled.
+var normalizedUnits = map[string]unitConversion{ //nolint:gochecknoglobals
+	// diagnosticdata (serverStatus).
+	"mongodb_ss_uptimeMillis":                              {name: "mongodb_instance_uptime_seconds", factor: 1e-3},
+	"mongodb_ss_mem_resident":                              {name: "mongodb_ss_mem_resident_bytes", factor: 1 << 20},
+	"mongodb_ss_mem_virtual":                               {name: "mongodb_ss_mem_virtual_bytes", factor: 1 << 20},
+	"mongodb_ss_metrics_repl_apply_batches_totalMillis":    {name: "mongodb_ss_metrics_repl_apply_batches_total_seconds", factor: 1e-3},
+	"mongodb_ss_metrics_repl_network_getmores_totalMillis": {name: "mongodb_ss_metrics_repl_network_getmores_total_seconds", factor: 1e-3},
+	"mongodb_ss_metrics_getLastError_wtime_totalMillis":    {name: "mongodb_ss_metrics_getLastError_wtime_total_seconds", factor: 1e-3},
+
+	// replicasetstatus.
+	"mongodb_mongod_replset_member_ping_ms": {name: "mongodb_mongod_replset_member_ping_seconds", factor: 1e-3},
+
+	// currentopmetrics.
+	"mongodb_currentop_query_uptime": {name: "mongodb_currentop_query_uptime_seconds", factor: 1e-6},
+
+	// lockstats and flowcontrol.
+	"mongodb_locks_time_acquiring_micros_total":        {name: "mongodb_locks_time_acquiring_seconds_total", factor: 1e-6},
+	"mongodb_flow_control_time_acquiring_micros_total": {name: "mongodb_flow_control_time_acquiring_seconds_total", factor: 1e-6},
+
+	// All the collectors.
+	"collector_scrape_time_ms": {name: "collector_scrape_time_seconds", factor: 1e-3},
+}
+
+// normalizeUnits returns a gatherer converting the metrics of g listed in normalizedUnits to seconds
+// and bytes. A metric is kept unchanged if g already has one with the new name, for example
+// mongodb_instance_uptime_seconds in compatible mode.
+func normalizeUnits(g prometheus.Gatherer) prometheus.Gatherer {
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		mfs, err := g.Gather()
+
+		names := make(map[string]bool, len(mfs))
+		for _, mf := range mfs {
+			names[mf.GetName()] = true
+		}
+
+		for _, mf := range mfs {
+			conv, ok := normalizedUnits[mf.GetName()]
+			if !ok || names[conv.name] {
+				continue
+			}
+
+			name := conv.name
+			mf.Name = &name
+
+			for _, m := range mf.GetMetric() {
+				scaleMetric(m, conv.factor)
+			}
+		}
+
+		sort.Slice(mfs, func(i, j int) bool { return mfs[i].GetName() < mfs[j].GetName() })
+
+		return mfs, err
+	})
+}
+
+func scaleMetric(m *dto.Metric, factor float64) {
+	var v float64
+	switch {
+	case m.GetCounter() != nil:
+		v = m.GetCounter().GetValue() * factor
+		m.Counter.Value = &v
+	case m.GetGauge() != nil:
+		v = m.GetGauge().GetValue() * factor
+		m.Gauge.Value = &v
+	case m.GetUntyped() != nil:
+		v = m.GetUntyped().GetValue() * factor
+		m.Untyped.Value = &v
+	}
+}
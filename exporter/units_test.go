@@ -0,0 +1,73 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeUnits(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(metricsCollector{
+		prometheus.MustNewConstMetric(prometheus.NewDesc("mongodb_ss_uptimeMillis", "serverStatus.", nil, nil),
+			prometheus.UntypedValue, 86400000),
+		prometheus.MustNewConstMetric(prometheus.NewDesc("mongodb_ss_mem_resident", "serverStatus.mem.", nil, nil),
+			prometheus.UntypedValue, 512),
+		prometheus.MustNewConstMetric(prometheus.NewDesc("mongodb_mongod_replset_member_ping_ms", "Ping.", []string{"name"}, nil),
+			prometheus.GaugeValue, 25, "rs0-2:27017"),
+		prometheus.MustNewConstMetric(prometheus.NewDesc("mongodb_flow_control_time_acquiring_micros_total", "Flow control.", nil, nil),
+			prometheus.CounterValue, 1500000),
+		// Metrics not in the mapping are unchanged.
+		prometheus.MustNewConstMetric(prometheus.NewDesc("mongodb_ss_connections", "serverStatus.connections.", []string{"conn_type"}, nil),
+			prometheus.UntypedValue, 10, "current"),
+	})
+
+	mfs, err := normalizeUnits(registry).Gather()
+	require.NoError(t, err)
+
+	values := make(map[string]float64)
+	for _, mf := range mfs {
+		values[mf.GetName()] = metricValue(mf.GetMetric()[0])
+	}
+
+	assert.Equal(t, map[string]float64{
+		"mongodb_instance_uptime_seconds":                   86400,
+		"mongodb_ss_mem_resident_bytes":                     512 * 1024 * 1024,
+		"mongodb_mongod_replset_member_ping_seconds":        0.025,
+		"mongodb_flow_control_time_acquiring_seconds_total": 1.5,
+		"mongodb_ss_connections":                            10,
+	}, values)
+
+	// In compatible mode, the uptime in seconds already exists so the milliseconds are kept.
+	registry.MustRegister(metricsCollector{
+		prometheus.MustNewConstMetric(prometheus.NewDesc("mongodb_instance_uptime_seconds", "Uptime.", nil, nil),
+			prometheus.CounterValue, 86400),
+	})
+
+	mfs, err = normalizeUnits(registry).Gather()
+	require.NoError(t, err)
+
+	names := make(map[string]bool)
+	for _, mf := range mfs {
+		names[mf.GetName()] = true
+	}
+	assert.True(t, names["mongodb_ss_uptimeMillis"])
+	assert.True(t, names["mongodb_instance_uptime_seconds"])
+}
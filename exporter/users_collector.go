@@ -0,0 +1,152 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type usersCollector struct {
+	ctx  context.Context
+	base *baseCollector
+}
+
+// newUsersCollector creates a collector for the number of users per database.
+func newUsersCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger) *usersCollector {
+	return &usersCollector{
+		ctx:  ctx,
+		base: newBaseCollector(client, logger),
+	}
+}
+
+func (d *usersCollector) Describe(ch chan<- *prometheus.Desc) {
+	d.base.Describe(d.ctx, ch, d.collect)
+}
+
+func (d *usersCollector) Collect(ch chan<- prometheus.Metric) {
+	d.base.Collect(ch)
+}
+
+func (d *usersCollector) collect(ch chan<- prometheus.Metric) {
+	defer measureCollectTime(ch, "mongodb", "users")()
+
+	logger := d.base.logger
+	client := d.base.client
+
+	var m bson.M
+	cmd := bson.D{{Key: "usersInfo", Value: bson.D{{Key: "forAllDBs", Value: true}}}}
+	err := client.Database("admin").RunCommand(d.ctx, cmd).Decode(&m)
+
+	switch {
+	case err == nil:
+		for _, metric := range databaseUsersMetrics(userCounts(m)) {
+			ch <- metric
+		}
+
+		return
+	case !isUnauthorized(err):
+		logger.Errorf("cannot get the users: %s", err)
+
+		return
+	}
+
+	// Without viewUser on the cluster, the users can still be listed for the databases where it is
+	// granted. In the other databases, only the own credentials are visible so they are skipped
+	// instead of being counted as 0.
+	logger.Debugf("cannot get the users of all the databases: %s", err)
+
+	dbs, err := databases(d.ctx, client, nil, nil)
+	if err != nil {
+		logger.Errorf("cannot get the databases to count the users: %s", err)
+
+		return
+	}
+
+	counts := make(map[string]int)
+	for _, db := range dbs {
+		var m bson.M
+		cmd := bson.D{{Key: "usersInfo", Value: 1}}
+		if err := client.Database(db).RunCommand(d.ctx, cmd).Decode(&m); err != nil {
+			if isUnauthorized(err) {
+				logger.Debugf("not allowed to get the users of %q: %s", db, err)
+
+				continue
+			}
+			logger.Errorf("cannot get the users of %q: %s", db, err)
+
+			continue
+		}
+
+		counts[db] = 0
+		for userDB, n := range userCounts(m) {
+			counts[userDB] += n
+		}
+	}
+
+	for _, metric := range databaseUsersMetrics(counts) {
+		ch <- metric
+	}
+}
+
+// userCounts returns the number of users per database from a usersInfo response.
+func userCounts(m bson.M) map[string]int {
+	counts := make(map[string]int)
+
+	users, ok := m["users"].(primitive.A)
+	if !ok {
+		return counts
+	}
+
+	for _, u := range users {
+		user, ok := u.(bson.M)
+		if !ok {
+			continue
+		}
+
+		if db, ok := user["db"].(string); ok {
+			counts[db]++
+		}
+	}
+
+	return counts
+}
+
+// databaseUsersMetrics returns mongodb_database_users for each database, sorted by database.
+func databaseUsersMetrics(counts map[string]int) []prometheus.Metric {
+	dbs := make([]string, 0, len(counts))
+	for db := range counts {
+		dbs = append(dbs, db)
+	}
+	sort.Strings(dbs)
+
+	d := prometheus.NewDesc("mongodb_database_users", "The number of users defined in the database.", []string{"db"}, nil)
+
+	metrics := make([]prometheus.Metric, 0, len(dbs))
+	for _, db := range dbs {
+		metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.GaugeValue, float64(counts[db]), db))
+	}
+
+	return metrics
+}
+
+var _ prometheus.Collector = (*usersCollector)(nil)
@@ -0,0 +1,69 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestUserCounts(t *testing.T) {
+	// usersInfo with forAllDBs.
+	m := bson.M{
+		"users": primitive.A{
+			bson.M{"_id": "admin.root", "user": "root", "db": "admin", "roles": primitive.A{}},
+			bson.M{"_id": "admin.exporter", "user": "exporter", "db": "admin", "roles": primitive.A{}},
+			bson.M{"_id": "shop.app", "user": "app", "db": "shop", "roles": primitive.A{}},
+		},
+		"ok": float64(1),
+	}
+
+	counts := userCounts(m)
+	assert.Equal(t, map[string]int{"admin": 2, "shop": 1}, counts)
+
+	// A database without users, listed by the per database fallback.
+	counts["test"] = 0
+
+	expected := `
+	# HELP mongodb_database_users The number of users defined in the database.
+	# TYPE mongodb_database_users gauge
+	mongodb_database_users{db="admin"} 2
+	mongodb_database_users{db="shop"} 1
+	mongodb_database_users{db="test"} 0` + "\n"
+
+	err := testutil.CollectAndCompare(metricsCollector(databaseUsersMetrics(counts)), strings.NewReader(expected))
+	assert.NoError(t, err)
+
+	assert.Empty(t, userCounts(bson.M{"ok": float64(1)}))
+}
+
+func TestUserStatsNotInCollectAll(t *testing.T) {
+	opts := &Opts{CollectAll: true}
+	e := &Exporter{opts: opts, lock: &sync.Mutex{}}
+
+	assert.False(t, collectorEnabled(e.collectorStates(typeMongod, *opts), collectorUsers))
+
+	opts = &Opts{CollectAll: true, EnableUserStats: true}
+	e = &Exporter{opts: opts, lock: &sync.Mutex{}}
+
+	assert.True(t, collectorEnabled(e.collectorStates(typeMongod, *opts), collectorUsers))
+}
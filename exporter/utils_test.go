@@ -19,8 +19,23 @@ import (
 	"strings"
 
 	"github.com/percona/exporter_shared/helpers"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
+// metricsCollector exposes a fixed list of metrics as a collector so the metrics built
+// from mocked documents can be checked with the testutil helpers.
+type metricsCollector []prometheus.Metric
+
+func (m metricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(m, ch)
+}
+
+func (m metricsCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, metric := range m {
+		ch <- metric
+	}
+}
+
 func filterMetrics(metrics []*helpers.Metric, filters []string) []*helpers.Metric {
 	res := make([]*helpers.Metric, 0, len(metrics))
 